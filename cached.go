@@ -0,0 +1,172 @@
+package simplefs
+
+import (
+	"container/list"
+	"io"
+	"os"
+	"sync"
+)
+
+// CacheStats reports the effectiveness of a CachedFS.
+type CacheStats struct {
+	Hits    int64 // Opens served from the cache
+	Misses  int64 // Opens served from the backing FS
+	Entries int   // Number of cached files
+	Bytes   int64 // Total size of cached files
+}
+
+// CachedFS is a read-through cache over a backing FS. See Cached.
+type CachedFS struct {
+	backing  FS
+	maxBytes int64
+
+	l       sync.Mutex
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+	size    int64
+	writes  uint64 // incremented on every invalidation
+	stats   CacheStats
+}
+
+type cacheEntry struct {
+	name string
+	b    []byte
+}
+
+// Cached returns a CachedFS that keeps the contents of files opened from
+// backing in memory, up to a total of maxBytes, evicting the least recently
+// used files first. On a miss the whole file is read from backing before
+// Open returns, except for files that are known (via Stat) to exceed
+// maxBytes, which are streamed from backing uncached. Create and Append
+// invalidate the cached entry for the path. ReadDir is not cached.
+func Cached(backing FS, maxBytes int64) *CachedFS {
+	return &CachedFS{
+		backing:  backing,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Stats returns the cache statistics so far.
+func (fs *CachedFS) Stats() CacheStats {
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	stats := fs.stats
+	stats.Entries = len(fs.entries)
+	stats.Bytes = fs.size
+	return stats
+}
+
+func (fs *CachedFS) Unwrap() FS {
+	return fs.backing
+}
+
+func (fs *CachedFS) Open(name string) (File, error) {
+	fs.l.Lock()
+	if elem, ok := fs.entries[name]; ok {
+		fs.lru.MoveToFront(elem)
+		fs.stats.Hits++
+		b := elem.Value.(*cacheEntry).b
+		fs.l.Unlock()
+		return newBytesFile(name, b), nil
+	}
+	fs.stats.Misses++
+	writes := fs.writes
+	fs.l.Unlock()
+
+	if info, err := Stat(fs.backing, name); err == nil && info.Size() > fs.maxBytes {
+		return fs.backing.Open(name)
+	}
+	f, err := fs.backing.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if isDirFile(f) {
+		return f, nil
+	}
+	b, err := io.ReadAll(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	fs.add(name, b, writes)
+	return newBytesFile(name, b), nil
+}
+
+// add caches b as the contents of name, unless the FS has been written to
+// since the contents were read.
+func (fs *CachedFS) add(name string, b []byte, writes uint64) {
+	if int64(len(b)) > fs.maxBytes {
+		return
+	}
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	if fs.writes != writes {
+		return
+	}
+	if _, ok := fs.entries[name]; ok {
+		return
+	}
+	fs.entries[name] = fs.lru.PushFront(&cacheEntry{name: name, b: b})
+	fs.size += int64(len(b))
+	for fs.size > fs.maxBytes {
+		fs.remove(fs.lru.Back())
+	}
+}
+
+func (fs *CachedFS) remove(elem *list.Element) {
+	entry := fs.lru.Remove(elem).(*cacheEntry)
+	delete(fs.entries, entry.name)
+	fs.size -= int64(len(entry.b))
+}
+
+func (fs *CachedFS) invalidate(name string) {
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	fs.writes++
+	if elem, ok := fs.entries[name]; ok {
+		fs.remove(elem)
+	}
+}
+
+func (fs *CachedFS) Stat(name string) (os.FileInfo, error) {
+	return Stat(fs.backing, name)
+}
+
+func (fs *CachedFS) Lstat(name string) (os.FileInfo, error) {
+	return Lstat(fs.backing, name)
+}
+
+func (fs *CachedFS) ReadDir(name string) ([]DirEntry, error) {
+	return fs.backing.ReadDir(name)
+}
+
+func (fs *CachedFS) Create(name string) (io.WriteCloser, error) {
+	fs.invalidate(name)
+	w, err := fs.backing.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.invalidatingWriter(name, w), nil
+}
+
+func (fs *CachedFS) Append(name string) (io.WriteCloser, error) {
+	fs.invalidate(name)
+	w, err := fs.backing.Append(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.invalidatingWriter(name, w), nil
+}
+
+// invalidatingWriter invalidates name again when w is closed, in case the
+// old contents were cached while w was open.
+func (fs *CachedFS) invalidatingWriter(name string, w io.WriteCloser) io.WriteCloser {
+	return &writeCloser{w: w, closeFn: func() error {
+		defer fs.invalidate(name)
+		return w.Close()
+	}}
+}
@@ -0,0 +1,66 @@
+package simplefs
+
+import (
+	"io"
+	"testing"
+)
+
+func TestCached(t *testing.T) {
+	backing := &MemFS{}
+	backing.SetString("a", "aaaa")
+	backing.SetString("b", "bbbb")
+	backing.SetString("c", "cccc")
+	backing.SetString("big", "0123456789")
+	fs := Cached(backing, 8)
+
+	read := func(name string) string {
+		t.Helper()
+		f, err := fs.Open(name)
+		if err != nil {
+			t.Fatalf("Open(%s) error: %v", name, err)
+		}
+		b, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatalf("Read(%s) error: %v", name, err)
+		}
+		return string(b)
+	}
+	assertStats := func(hits, misses int64, entries int) {
+		t.Helper()
+		stats := fs.Stats()
+		if stats.Hits != hits || stats.Misses != misses || stats.Entries != entries {
+			t.Fatalf("Stats() returned %+v, want %d hits, %d misses, %d entries", stats, hits, misses, entries)
+		}
+	}
+
+	read("a")
+	read("a")
+	assertStats(1, 1, 1)
+	read("b")
+	read("a") // a is now the most recently used
+	read("c") // evicts b
+	assertStats(2, 3, 2)
+	read("b")
+	assertStats(2, 4, 2)
+	read("big") // larger than the budget, never cached
+	read("big")
+	assertStats(2, 6, 2)
+
+	// Writes invalidate the cached entry
+	if err := WriteString(fs, "b", "BBBB"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if got := read("b"); got != "BBBB" {
+		t.Fatalf("Read after write returned %q", got)
+	}
+	w, _ := fs.Append("b")
+	_, _ = w.Write([]byte("!"))
+	_ = w.Close()
+	if got := read("b"); got != "BBBB!" {
+		t.Fatalf("Read after append returned %q", got)
+	}
+
+	if msg := RunFileSystemTest(Cached(&MemFS{}, 1024)); msg != "" {
+		t.Fatal(msg)
+	}
+}
@@ -0,0 +1,57 @@
+package simplefs
+
+import (
+	"bytes"
+	"io"
+)
+
+// CachingOpen opens the named file from cache if it has been cached before,
+// and otherwise from backing. Bytes read from backing are collected as they
+// are read, and once the file has been read to the end the complete contents
+// are stored in cache, so that subsequent opens are served from there. Files
+// that are only partially read before being closed are not cached.
+// Directories are always opened from backing.
+func CachingOpen(backing FS, cache *MemFS, name string) (File, error) {
+	if info, err := cache.Stat(name); err == nil && !info.IsDir() {
+		return cache.Open(name)
+	}
+	f, err := backing.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if isDirFile(f) {
+		return f, nil
+	}
+	return &cachingFile{f: f, cache: cache, name: name}, nil
+}
+
+type cachingFile struct {
+	f     File
+	cache *MemFS
+	name  string
+	buf   bytes.Buffer
+	done  bool
+}
+
+func (f *cachingFile) Read(p []byte) (int, error) {
+	n, err := f.f.Read(p)
+	if f.done {
+		return n, err
+	}
+	f.buf.Write(p[:n])
+	if err == io.EOF {
+		f.done = true
+		if cacheErr := WriteFile(f.cache, f.name, f.buf.Bytes()); cacheErr != nil {
+			return n, cacheErr
+		}
+	}
+	return n, err
+}
+
+func (f *cachingFile) Close() error {
+	return f.f.Close()
+}
+
+func (f *cachingFile) ReadDir(n int) ([]DirEntry, error) {
+	return f.f.ReadDir(n)
+}
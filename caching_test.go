@@ -0,0 +1,47 @@
+package simplefs
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCachingOpen(t *testing.T) {
+	backing := &MemFS{}
+	backing.SetString("dir/file", "contents")
+	cache := &MemFS{}
+
+	// A partial read does not populate the cache
+	f, err := CachingOpen(backing, cache, "dir/file")
+	if err != nil {
+		t.Fatalf("CachingOpen() error: %v", err)
+	}
+	if _, err := f.Read(make([]byte, 3)); err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	_ = f.Close()
+	if _, err := cache.Stat("dir/file"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Partially read file was cached")
+	}
+
+	// A complete read does
+	f, _ = CachingOpen(backing, cache, "dir/file")
+	if b, err := io.ReadAll(f); err != nil || string(b) != "contents" {
+		t.Fatalf("ReadAll() returned %q, %v", b, err)
+	}
+	_ = f.Close()
+	if b, err := ReadFile(cache, "dir/file"); err != nil || string(b) != "contents" {
+		t.Fatalf("Cached file contains %q, %v", b, err)
+	}
+
+	// Subsequent opens are served from the cache
+	backing.SetString("dir/file", "changed")
+	f, _ = CachingOpen(backing, cache, "dir/file")
+	if b, _ := io.ReadAll(f); string(b) != "contents" {
+		t.Fatalf("ReadAll() returned %q, want cached contents", b)
+	}
+
+	if _, err := CachingOpen(backing, cache, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("CachingOpen() on missing file returned %v, want ErrNotFound", err)
+	}
+}
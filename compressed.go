@@ -0,0 +1,91 @@
+package simplefs
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// Compressed returns an FS that transparently gzip-compresses file contents
+// in fs. Callers read and write plain data while fs stores compressed bytes.
+//
+// Each call to Create or Append writes a separate gzip member, so an appended
+// file is a concatenation of gzip streams. Open decodes all members in
+// sequence, so the file reads back as the concatenation of everything written
+// to it. ReadDir and directory handles are passed through unchanged.
+func Compressed(fs FS) FS {
+	return &compressedFS{fs: fs}
+}
+
+type compressedFS struct {
+	fs FS
+}
+
+func (fs *compressedFS) Unwrap() FS {
+	return fs.fs
+}
+
+func (fs *compressedFS) Open(name string) (File, error) {
+	f, err := fs.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if isDirFile(f) {
+		return f, nil
+	}
+	return &compressedFile{f: f}, nil
+}
+
+func (fs *compressedFS) ReadDir(name string) ([]DirEntry, error) {
+	return fs.fs.ReadDir(name)
+}
+
+func (fs *compressedFS) Create(name string) (io.WriteCloser, error) {
+	w, err := fs.fs.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return newCompressedWriter(w), nil
+}
+
+func (fs *compressedFS) Append(name string) (io.WriteCloser, error) {
+	w, err := fs.fs.Append(name)
+	if err != nil {
+		return nil, err
+	}
+	return newCompressedWriter(w), nil
+}
+
+func newCompressedWriter(w io.WriteCloser) io.WriteCloser {
+	zw := gzip.NewWriter(w)
+	return &writeCloser{w: zw, closeFn: func() error {
+		err := zw.Close()
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}}
+}
+
+type compressedFile struct {
+	f  File
+	zr *gzip.Reader
+}
+
+func (f *compressedFile) Read(p []byte) (int, error) {
+	if f.zr == nil {
+		zr, err := gzip.NewReader(f.f)
+		if err != nil {
+			return 0, err
+		}
+		f.zr = zr
+	}
+	return f.zr.Read(p)
+}
+
+func (f *compressedFile) Close() error {
+	return f.f.Close()
+}
+
+func (f *compressedFile) ReadDir(n int) ([]DirEntry, error) {
+	return f.f.ReadDir(n)
+}
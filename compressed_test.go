@@ -0,0 +1,47 @@
+package simplefs
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressed(t *testing.T) {
+	mem := &MemFS{}
+	fs := Compressed(mem)
+
+	plain := bytes.Repeat([]byte("compressible "), 100)
+	if err := WriteFile(fs, "dir/file", plain); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	w, err := fs.Append("dir/file")
+	if err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+	if _, err := w.Write([]byte("appended")); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	got, err := ReadFile(fs, "dir/file")
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	want := append(plain, "appended"...)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("ReadFile() returned %q, want %q", got, want)
+	}
+
+	stored, err := ReadFile(mem, "dir/file")
+	if err != nil {
+		t.Fatalf("ReadFile() on backing FS error: %v", err)
+	}
+	if len(stored) >= len(want) {
+		t.Fatalf("Stored %d bytes for %d bytes of plain data", len(stored), len(want))
+	}
+
+	if msg := RunFileSystemTest(Compressed(&MemFS{})); msg != "" {
+		t.Fatal(msg)
+	}
+}
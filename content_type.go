@@ -0,0 +1,40 @@
+package simplefs
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+)
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// ContentType returns the MIME type of the named file. It is detected from
+// the first 512 bytes with http.DetectContentType, and if that only finds
+// "application/octet-stream", from the extension of name with
+// mime.TypeByExtension. The file is opened separately, so handles held by the
+// caller are not read from. It returns an error wrapping ErrIsDir if name is
+// a directory.
+func ContentType(fs FS, name string) (string, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	if isDirFile(f) {
+		return "", &FSError{Op: "contenttype", Path: name, Err: ErrIsDir}
+	}
+	b := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	contentType := http.DetectContentType(b[:n])
+	if contentType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(path.Ext(name)); byExt != "" {
+			contentType = byExt
+		}
+	}
+	return contentType, nil
+}
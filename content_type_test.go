@@ -0,0 +1,36 @@
+package simplefs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestContentType(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{
+		"page.html":  "<!DOCTYPE html><html><body>hello</body></html>",
+		"notes":      "plain text",
+		"image.png":  "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR",
+		"data.pdf":   "\x00\x01\x02 not sniffable",
+		"data.bin":   "\x00\x01\x02",
+		"dir/nested": "",
+	})
+	for name, want := range map[string]string{
+		"page.html": "text/html; charset=utf-8",
+		"notes":     "text/plain; charset=utf-8",
+		"image.png": "image/png",
+		"data.pdf":  "application/pdf",
+		"data.bin":  "application/octet-stream",
+	} {
+		got, err := ContentType(fs, name)
+		if err != nil || got != want {
+			t.Fatalf("ContentType(%s) returned %q, %v, want %q, nil", name, got, err, want)
+		}
+	}
+
+	if _, err := ContentType(fs, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ContentType(missing) returned %v, want ErrNotFound", err)
+	}
+	if _, err := ContentType(fs, "dir"); !errors.Is(err, ErrIsDir) {
+		t.Fatalf("ContentType(dir) returned %v, want ErrIsDir", err)
+	}
+}
@@ -0,0 +1,246 @@
+package simplefs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+)
+
+// CopyFile copies the contents of srcName in src to dstName in dst. The
+// destination is created or truncated.
+func CopyFile(dst FS, dstName string, src FS, srcName string) (err error) {
+	return copyFile(dst, dstName, src, srcName, nil)
+}
+
+// copyFile is CopyFile, calling onWrite with the size of every write to dst
+// if it is not nil.
+func copyFile(dst FS, dstName string, src FS, srcName string, onWrite func(n int)) error {
+	r, err := src.Open(srcName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	w, err := dst.Create(dstName)
+	if err != nil {
+		return err
+	}
+	var from io.Reader = r
+	var to io.Writer = w
+	if onWrite != nil {
+		// Hide io.WriterTo, which could copy the file in a single write.
+		from = struct{ io.Reader }{r}
+		to = &progressWriter{w: w, onWrite: onWrite}
+	}
+	if _, err := io.Copy(to, from); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// progressWriter calls onWrite with the number of bytes of every write.
+type progressWriter struct {
+	w       io.Writer
+	onWrite func(n int)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.onWrite(n)
+	return n, err
+}
+
+// ResumableCopy copies srcName in src to dstName in dst, resuming an earlier
+// interrupted copy. If dstName exists and its contents are a prefix of
+// srcName, only the remaining bytes are appended to it. Otherwise dstName is
+// created or truncated and the whole file is copied. The existing prefix is
+// verified by reading it from both files, so only the bytes after it are
+// written. It returns the number of bytes written to dst.
+func ResumableCopy(dst FS, dstName string, src FS, srcName string) (int64, error) {
+	r, err := src.Open(srcName)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = r.Close() }()
+
+	resume, err := isPrefixOf(dst, dstName, r)
+	if err != nil {
+		return 0, err
+	}
+	var w io.WriteCloser
+	if resume {
+		w, err = dst.Append(dstName)
+	} else {
+		// r has been partly consumed by the comparison, so start over. The
+		// new reader is assigned to r only once it is open, since the
+		// deferred Close would panic on a nil r.
+		var reopened File
+		if reopened, err = src.Open(srcName); err != nil {
+			return 0, err
+		}
+		_ = r.Close()
+		r = reopened
+		w, err = dst.Create(dstName)
+	}
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		_ = w.Close()
+		return n, err
+	}
+	return n, w.Close()
+}
+
+// isPrefixOf reports whether the contents of the named file in fs are a
+// prefix of what r returns. A missing file is an empty prefix. When it
+// returns true, r has been read up to the end of the prefix.
+func isPrefixOf(fs FS, name string, r io.Reader) (bool, error) {
+	f, err := fs.Open(name)
+	if errors.Is(err, ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+
+	const bufSize = 32 * 1024
+	have, want := make([]byte, bufSize), make([]byte, bufSize)
+	for {
+		n, err := io.ReadFull(f, have)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return false, err
+		}
+		if n > 0 {
+			if m, err := io.ReadFull(r, want[:n]); err != nil || !bytes.Equal(have[:n], want[:m]) {
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					err = nil
+				}
+				return false, err
+			}
+		}
+		if err != nil {
+			return true, nil
+		}
+	}
+}
+
+// CopyTree copies every file in the tree rooted at root in src to the same
+// path in dst. Empty directories are not copied.
+func CopyTree(dst, src FS, root string) error {
+	return WalkFiles(src, root, func(name string) error {
+		return CopyFile(dst, name, src, name)
+	})
+}
+
+// progressInterval is the number of bytes CopyTreeProgress copies between
+// reports within a file.
+const progressInterval = 1 << 20
+
+// CopyTreeProgress is like CopyTree, but calls progress after copying each
+// file, and every 1 MiB within large files, with the path being copied, the
+// number of bytes copied so far and the total number of bytes to copy.
+//
+// The total is computed with DiskUsage before copying, which only reads the
+// metadata of src, not the contents. If it cannot be computed, for example
+// because src does not implement Stater, the total is reported as -1.
+func CopyTreeProgress(dst, src FS, root string, progress func(path string, bytesCopied, totalBytes int64)) error {
+	total, err := DiskUsage(src, root)
+	if err != nil {
+		total = -1
+	}
+	var copied, reported int64
+	return WalkFiles(src, root, func(name string) error {
+		err := copyFile(dst, name, src, name, func(n int) {
+			copied += int64(n)
+			if copied-reported >= progressInterval {
+				reported = copied
+				progress(name, copied, total)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		reported = copied
+		progress(name, copied, total)
+		return nil
+	})
+}
+
+// MoveAcross moves the file srcName in src to dstName in dst, which may be a
+// different kind of FS. The file is copied with CopyFile and removed from src
+// only if the copy succeeds, including closing the destination, so a failed
+// move leaves the source intact. If dst and src are the same FS and it
+// implements Renamer, the file is renamed instead. Moving a file onto itself,
+// which would truncate it before copying, is an error; this is detected for
+// two OsFS values sharing a directory with os.SameFile. src must implement
+// Remover.
+func MoveAcross(dst FS, dstName string, src FS, srcName string) error {
+	if _, ok := src.(Remover); !ok {
+		return fmt.Errorf("cannot move '%s'. %T does not implement Remover: %w", srcName, src, ErrNotImplemented)
+	}
+	same := sameFS(dst, src)
+	if r, ok := src.(Renamer); ok && same {
+		return r.Rename(srcName, dstName)
+	}
+	if same && CleanPath(dstName) == CleanPath(srcName) || sameFile(dst, dstName, src, srcName) {
+		return fmt.Errorf("cannot move '%s' to '%s'. Source and destination are the same file", srcName, dstName)
+	}
+	if err := CopyFile(dst, dstName, src, srcName); err != nil {
+		return err
+	}
+	return Remove(src, srcName)
+}
+
+// sameFS reports whether a and b are the same FS. Only pointers are compared,
+// since comparing values of other types with == can panic.
+func sameFS(a, b FS) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.Kind() == reflect.Ptr && va.Type() == vb.Type() && va.Pointer() == vb.Pointer()
+}
+
+// sameFile reports whether aName in a and bName in b are the same file on
+// disk, according to os.SameFile. It returns false if either cannot be
+// stated.
+func sameFile(a FS, aName string, b FS, bName string) bool {
+	aInfo, err := Stat(a, aName)
+	if err != nil {
+		return false
+	}
+	bInfo, err := Stat(b, bName)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+// MoveAll copies the tree rooted at root from src to dst, and then removes it
+// from src. The source is only removed once every file has been copied, so a
+// failed copy leaves src intact. src must implement Remover.
+func MoveAll(dst, src FS, root string) error {
+	if _, ok := src.(Remover); !ok {
+		return fmt.Errorf("cannot move '%s'. %T does not implement Remover: %w", root, src, ErrNotImplemented)
+	}
+	if err := CopyTree(dst, src, root); err != nil {
+		return err
+	}
+	if path.Clean(root) == "." {
+		entries, err := src.ReadDir(root)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := RemoveAll(src, entry.Name()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return RemoveAll(src, root)
+}
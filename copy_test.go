@@ -0,0 +1,218 @@
+package simplefs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMoveAll(t *testing.T) {
+	newSrc := func() *MemFS {
+		src := &MemFS{}
+		src.SetString("keep", "keep")
+		src.SetString("dir/a", "a")
+		src.SetString("dir/sub/b", "b")
+		return src
+	}
+
+	src, dst := newSrc(), &MemFS{}
+	if err := MoveAll(dst, src, "dir"); err != nil {
+		t.Fatalf("MoveAll() error: %v", err)
+	}
+	for name, want := range map[string]string{"dir/a": "a", "dir/sub/b": "b"} {
+		if b, err := ReadFile(dst, name); err != nil || string(b) != want {
+			t.Fatalf("ReadFile(dst, %s) returned %q, %v", name, b, err)
+		}
+	}
+	if _, err := src.Stat("dir"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat(src, dir) after move returned %v, want ErrNotFound", err)
+	}
+	if _, err := src.Stat("keep"); err != nil {
+		t.Fatalf("Stat(src, keep) after move error: %v", err)
+	}
+
+	// A copy that fails partway leaves the source intact
+	src = newSrc()
+	failing := WithNameLimits(&MemFS{}, 1, 0) // rejects "sub"
+	if err := MoveAll(failing, src, "dir"); !errors.Is(err, ErrNameTooLong) {
+		t.Fatalf("MoveAll() returned %v, want ErrNameTooLong", err)
+	}
+	if !src.Equal(newSrc()) {
+		t.Fatalf("Source changed after failed move")
+	}
+}
+
+func TestResumableCopy(t *testing.T) {
+	data := make([]byte, 100*1024)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	src := NewMemFS(map[string][]byte{"file": data})
+
+	tests := []struct {
+		name    string
+		partial []byte // Existing destination contents, nil for none
+		want    int64  // Bytes written by ResumableCopy
+	}{
+		{"Missing", nil, int64(len(data))},
+		{"Prefix", data[:40*1024], int64(len(data) - 40*1024)},
+		{"Complete", data, 0},
+		{"Mismatch", []byte("not a prefix"), int64(len(data))},
+		{"Longer", append(append([]byte{}, data...), 'x'), int64(len(data))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dst := &MemFS{}
+			if test.partial != nil {
+				dst.SetBytes("copy", test.partial)
+			}
+			n, err := ResumableCopy(dst, "copy", src, "file")
+			if err != nil {
+				t.Fatalf("ResumableCopy() error: %v", err)
+			}
+			if n != test.want {
+				t.Fatalf("ResumableCopy() returned %d, want %d", n, test.want)
+			}
+			if b, err := ReadFile(dst, "copy"); err != nil || !bytes.Equal(b, data) {
+				t.Fatalf("ReadFile() returned %d bytes, %v, want a copy of the source", len(b), err)
+			}
+		})
+	}
+}
+
+// openLimitFS fails every Open after the first n.
+type openLimitFS struct {
+	FS
+	n int
+}
+
+func (fs *openLimitFS) Open(name string) (File, error) {
+	if fs.n == 0 {
+		return nil, fmt.Errorf("open limit reached")
+	}
+	fs.n--
+	return fs.FS.Open(name)
+}
+
+func TestResumableCopyReopenError(t *testing.T) {
+	src := &openLimitFS{FS: NewMemFSFromStrings(map[string]string{"file": "data"}), n: 1}
+	dst := NewMemFSFromStrings(map[string]string{"copy": "not a prefix"})
+	if _, err := ResumableCopy(dst, "copy", src, "file"); err == nil {
+		t.Fatalf("ResumableCopy() returned nil error, want the error of the second Open")
+	}
+}
+
+func TestCopyTreeProgress(t *testing.T) {
+	src := NewMemFSFromStrings(map[string]string{
+		"a":       "0123456789",
+		"dir/big": strings.Repeat("x", 5<<19),
+		"dir/c":   "01234",
+	})
+	type report struct {
+		path          string
+		copied, total int64
+	}
+	var reports []report
+	dst := &MemFS{}
+	err := CopyTreeProgress(dst, src, ".", func(path string, copied, total int64) {
+		reports = append(reports, report{path, copied, total})
+	})
+	if err != nil {
+		t.Fatalf("CopyTreeProgress() error: %v", err)
+	}
+	if !dst.Equal(src) {
+		t.Fatalf("CopyTreeProgress() did not copy the tree")
+	}
+	const total = 15 + 5<<19
+	var big int
+	var prev report
+	for i, r := range reports {
+		if r.total != total || r.copied < prev.copied {
+			t.Fatalf("Report %d is %+v after %+v, want increasing bytes of %d", i, r, prev, total)
+		}
+		prev = r
+		if r.path == "dir/big" {
+			big++
+		}
+	}
+	if big < 3 {
+		t.Fatalf("Got %d reports while copying a 2.5 MiB file, want at least 3", big)
+	}
+	if last := reports[len(reports)-1]; last.path != "dir/c" || last.copied != total {
+		t.Fatalf("Last report is %+v, want all %d bytes of dir/c", last, total)
+	}
+
+	// Without Stat the total is unknown.
+	reports = nil
+	if err := CopyTreeProgress(&MemFS{}, struct{ FS }{src}, "dir", func(path string, copied, total int64) {
+		reports = append(reports, report{path, copied, total})
+	}); err != nil {
+		t.Fatalf("CopyTreeProgress() error: %v", err)
+	}
+	if last := reports[len(reports)-1]; last.copied != 5+5<<19 || last.total != -1 {
+		t.Fatalf("Last report without Stat is %+v, want %d bytes of -1", last, 5+5<<19)
+	}
+}
+
+func TestMoveAcross(t *testing.T) {
+	src := NewMemFSFromStrings(map[string]string{"file": "contents", "other": "x"})
+	dst := OsFS(t.TempDir())
+	if err := MoveAcross(dst, "dir/moved", src, "file"); err != nil {
+		t.Fatalf("MoveAcross() error: %v", err)
+	}
+	if b, err := ReadFile(dst, "dir/moved"); err != nil || string(b) != "contents" {
+		t.Fatalf("Destination contains %q, %v", b, err)
+	}
+	if _, err := src.Bytes("file"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Source still exists after MoveAcross(): %v", err)
+	}
+
+	// A failed Create leaves the source intact.
+	if err := WriteString(dst, "dir/sub/file", ""); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if err := MoveAcross(dst, "dir/sub", src, "other"); err == nil {
+		t.Fatalf("MoveAcross() onto a directory returned nil error")
+	}
+	// So does a failed write.
+	if err := MoveAcross(WithQuota(&MemFS{}, 0), "other", src, "other"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("MoveAcross() past the quota returned %v, want ErrQuotaExceeded", err)
+	}
+	if b, err := src.Bytes("other"); err != nil || string(b) != "x" {
+		t.Fatalf("Source contains %q, %v after failed moves", b, err)
+	}
+
+	if err := MoveAcross(src, "renamed", src, "other"); err != nil {
+		t.Fatalf("MoveAcross() within one FS error: %v", err)
+	}
+	if b, err := src.Bytes("renamed"); err != nil || string(b) != "x" {
+		t.Fatalf("Renamed file contains %q, %v", b, err)
+	}
+
+	// Two OsFS values for one directory must not copy a file onto itself.
+	dir := t.TempDir()
+	if err := WriteString(OsFS(dir), "f", "data"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if err := MoveAcross(OsFS(dir), "f", OsFS(dir), "./f"); err == nil {
+		t.Fatalf("MoveAcross() of a file onto itself returned nil error")
+	}
+	if b, err := ReadFile(OsFS(dir), "f"); err != nil || string(b) != "data" {
+		t.Fatalf("File contains %q, %v after moving it onto itself", b, err)
+	}
+
+	// FS values that cannot be compared with == are copied.
+	type uncomparableFS struct {
+		*MemFS
+		_ []int
+	}
+	nc := uncomparableFS{MemFS: NewMemFSFromStrings(map[string]string{"a": "a"})}
+	if err := MoveAcross(nc, "b", nc, "a"); err != nil {
+		t.Fatalf("MoveAcross() with an uncomparable FS error: %v", err)
+	}
+	if b, err := nc.Bytes("b"); err != nil || string(b) != "a" {
+		t.Fatalf("Moved file contains %q, %v", b, err)
+	}
+}
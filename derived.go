@@ -0,0 +1,60 @@
+package simplefs
+
+import (
+	"errors"
+	"io"
+)
+
+// DerivedRule reports whether name can be derived from another file. If ok
+// is true, the contents of name are those of source passed through
+// transform.
+type DerivedRule func(name string) (source string, transform func([]byte) ([]byte, error), ok bool)
+
+// WithDerived returns an FS serving virtual files generated on the fly. If
+// opening a name in fs returns ErrNotFound and rule reports that the name is
+// derivable, the source file is read and the transformed contents are
+// returned instead. Files that exist in fs always take precedence. Derived
+// files are not listed by ReadDir, and writes go straight to fs.
+func WithDerived(fs FS, rule DerivedRule) FS {
+	return &derivedFS{fs: fs, rule: rule}
+}
+
+type derivedFS struct {
+	fs   FS
+	rule DerivedRule
+}
+
+func (fs *derivedFS) Unwrap() FS {
+	return fs.fs
+}
+
+func (fs *derivedFS) Open(name string) (File, error) {
+	f, err := fs.fs.Open(name)
+	if !errors.Is(err, ErrNotFound) {
+		return f, err
+	}
+	source, transform, ok := fs.rule(name)
+	if !ok {
+		return nil, err
+	}
+	b, err := ReadFile(fs.fs, source)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = transform(b); err != nil {
+		return nil, err
+	}
+	return newBytesFile(name, b), nil
+}
+
+func (fs *derivedFS) ReadDir(name string) ([]DirEntry, error) {
+	return fs.fs.ReadDir(name)
+}
+
+func (fs *derivedFS) Create(name string) (io.WriteCloser, error) {
+	return fs.fs.Create(name)
+}
+
+func (fs *derivedFS) Append(name string) (io.WriteCloser, error) {
+	return fs.fs.Append(name)
+}
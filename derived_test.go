@@ -0,0 +1,48 @@
+package simplefs
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithDerived(t *testing.T) {
+	mem := NewMemFSFromStrings(map[string]string{
+		"app.js":     "function  hello ( ) { }",
+		"lib.js":     "lib",
+		"lib.min.js": "materialized",
+		"broken.js":  "",
+	})
+	errBroken := errors.New("broken")
+	fs := WithDerived(mem, func(name string) (string, func([]byte) ([]byte, error), bool) {
+		if !strings.HasSuffix(name, ".min.js") {
+			return "", nil, false
+		}
+		return strings.TrimSuffix(name, ".min.js") + ".js", func(b []byte) ([]byte, error) {
+			if len(b) == 0 {
+				return nil, errBroken
+			}
+			return bytes.Join(bytes.Fields(b), nil), nil
+		}, true
+	})
+
+	for name, want := range map[string]string{
+		"app.min.js": "functionhello(){}",
+		"lib.min.js": "materialized",
+		"app.js":     "function  hello ( ) { }",
+	} {
+		if b, err := ReadFile(fs, name); err != nil || string(b) != want {
+			t.Fatalf("ReadFile(%s) returned %q, %v, want %q", name, b, err, want)
+		}
+	}
+	if _, err := ReadFile(fs, "missing.min.js"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ReadFile() of derived file with missing source returned %v, want ErrNotFound", err)
+	}
+	if _, err := ReadFile(fs, "missing.css"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ReadFile() of underivable file returned %v, want ErrNotFound", err)
+	}
+	if _, err := ReadFile(fs, "broken.min.js"); !errors.Is(err, errBroken) {
+		t.Fatalf("ReadFile() with failing transform returned %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package simplefs
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+// readDirIterBatch is the number of entries a DirIterator reads from the
+// backend at a time.
+const readDirIterBatch = 256
+
+// DirIterator is implemented by file systems that can list a directory
+// without materializing all of its entries at once.
+//
+// ReadDirIter returns a function that calls yield for each entry in the named
+// directory until yield returns false. It has the shape of iter.Seq2, so it
+// can be ranged over directly on Go 1.23 and later. If the directory cannot be
+// read part way through, yield is called once with a nil DirEntry and the
+// error, and iteration stops.
+type DirIterator interface {
+	ReadDirIter(name string) (func(yield func(DirEntry, error) bool), error)
+}
+
+// ReadDirIter returns an iterator over the entries of the named directory. If
+// fs does not implement DirIterator the entries are read with fs.ReadDir, so
+// the directory is buffered as before.
+func ReadDirIter(fs FS, name string) (func(yield func(DirEntry, error) bool), error) {
+	if it, ok := fs.(DirIterator); ok {
+		return it.ReadDirIter(name)
+	}
+	entries, err := fs.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return func(yield func(DirEntry, error) bool) {
+		for _, entry := range entries {
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}, nil
+}
+
+// ReadDirIter yields the entries of the named directory in sorted order,
+// holding the lock only while copying out each batch. Entries added or
+// removed during iteration may or may not be seen, but an entry is never
+// yielded twice.
+func (fs *MemFS) ReadDirIter(name string) (func(yield func(DirEntry, error) bool), error) {
+	path, err := memPath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	fs.init()
+	fs.l.RLock()
+	node := fs.root.Get(path...)
+	fs.l.RUnlock()
+
+	if node == nil || !node.IsDirectory() {
+		return nil, notFound("readdir", name)
+	}
+
+	return func(yield func(DirEntry, error) bool) {
+		var last string
+		for first := true; ; first = false {
+			fs.l.RLock()
+			i := 0
+			if !first {
+				i = sort.Search(len(node.Children), func(i int) bool { return node.Children[i].Name > last })
+			}
+			batch := make([]DirEntry, 0, readDirIterBatch)
+			for ; i < len(node.Children) && len(batch) < readDirIterBatch; i++ {
+				batch = append(batch, newDirEntry(node.Children[i].FileInfo()))
+			}
+			fs.l.RUnlock()
+
+			for _, entry := range batch {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+			if len(batch) < readDirIterBatch {
+				return
+			}
+			last = batch[len(batch)-1].Name()
+		}
+	}, nil
+}
+
+// ReadDirIter yields the entries of the named directory in batches read with
+// os.File.Readdir. Unlike ReadDir, the entries are in directory order rather
+// than sorted, since sorting would require reading the whole directory. The
+// directory is opened each time the iterator is called, and closed when it
+// returns, so an iterator that is never called holds no file descriptor.
+func (fs *osFs) ReadDirIter(name string) (func(yield func(DirEntry, error) bool), error) {
+	p, err := fs.path("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(p); err != nil {
+		if os.IsNotExist(err) {
+			return nil, notFound("readdir", name)
+		}
+		return nil, err
+	}
+	return func(yield func(DirEntry, error) bool) {
+		f, err := os.Open(p)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer f.Close()
+		for {
+			infos, err := f.Readdir(readDirIterBatch)
+			for _, info := range infos {
+				if !yield(newDirEntry(info), nil) {
+					return
+				}
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,66 @@
+package simplefs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestReadDirIter(t *testing.T) {
+	const n = readDirIterBatch*2 + 10
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		var want []string
+		for i := 0; i < n; i++ {
+			fileName := fmt.Sprintf("f%04d", i)
+			if err := WriteString(fs, "dir/"+fileName, ""); err != nil {
+				t.Fatalf("%s: WriteString() error: %v", name, err)
+			}
+			want = append(want, fileName)
+		}
+
+		seq, err := ReadDirIter(fs, "dir")
+		if err != nil {
+			t.Fatalf("%s: ReadDirIter() error: %v", name, err)
+		}
+		var got []string
+		seq(func(entry DirEntry, err error) bool {
+			if err != nil {
+				t.Fatalf("%s: ReadDirIter() yielded error: %v", name, err)
+			}
+			got = append(got, entry.Name())
+			return true
+		})
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("%s: ReadDirIter() yielded %d entries, want %d", name, len(got), len(want))
+		}
+
+		// The iterator can be called again, and reads the directory anew.
+		got = got[:0]
+		seq(func(entry DirEntry, err error) bool {
+			if err != nil {
+				t.Fatalf("%s: ReadDirIter() yielded error on second call: %v", name, err)
+			}
+			got = append(got, entry.Name())
+			return true
+		})
+		if len(got) != n {
+			t.Fatalf("%s: ReadDirIter() yielded %d entries on second call, want %d", name, len(got), n)
+		}
+
+		count := 0
+		seq, _ = ReadDirIter(fs, "dir")
+		seq(func(entry DirEntry, err error) bool {
+			count++
+			return count < 3
+		})
+		if count != 3 {
+			t.Fatalf("%s: ReadDirIter() yielded %d entries after stop, want 3", name, count)
+		}
+
+		if _, err := ReadDirIter(fs, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: ReadDirIter(missing) returned %v, want %v", name, err, ErrNotFound)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package simplefs
+
+import (
+	"path"
+	"time"
+)
+
+// DirSummary holds aggregate statistics about a directory tree.
+type DirSummary struct {
+	FileCount     int       // Number of files in the tree
+	DirCount      int       // Number of directories in the tree, not counting the root
+	TotalBytes    int64     // Total size of all files
+	LatestModTime time.Time // Latest modification time of any file
+}
+
+// DirStats computes a DirSummary for the tree rooted at dir in a single
+// recursive walk. fs must implement Stater so that sizes and modification
+// times can be read.
+func DirStats(fs FS, dir string) (DirSummary, error) {
+	var summary DirSummary
+	err := summarizeDir(fs, dir, &summary)
+	return summary, err
+}
+
+func summarizeDir(fs FS, dir string, summary *DirSummary) error {
+	entries, err := fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		if entry.IsDir() {
+			summary.DirCount++
+			if err := summarizeDir(fs, name, summary); err != nil {
+				return err
+			}
+			continue
+		}
+		info, err := Stat(fs, name)
+		if err != nil {
+			return err
+		}
+		summary.FileCount++
+		summary.TotalBytes += info.Size()
+		if info.ModTime().After(summary.LatestModTime) {
+			summary.LatestModTime = info.ModTime()
+		}
+	}
+	return nil
+}
+
+// DiskUsage returns the total size of the files in the tree rooted at dir.
+// Directories contribute zero bytes themselves. A *MemFS is summed directly;
+// other file systems are walked as by DirStats and must implement Stater.
+func DiskUsage(fs FS, dir string) (int64, error) {
+	if mem, ok := fs.(*MemFS); ok {
+		return mem.DiskUsage(dir)
+	}
+	summary, err := DirStats(fs, dir)
+	return summary.TotalBytes, err
+}
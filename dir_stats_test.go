@@ -0,0 +1,71 @@
+package simplefs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDirStats(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now().Add(-time.Second)
+			for name, contents := range map[string]string{
+				"outside":     "not counted",
+				"dir/a":       "a",
+				"dir/sub/bb":  "bb",
+				"dir/sub/ccc": "ccc",
+				"dir/e/f/g":   "",
+			} {
+				if err := WriteString(fs, name, contents); err != nil {
+					t.Fatalf("WriteString(%s) error: %v", name, err)
+				}
+			}
+			got, err := DirStats(fs, "dir")
+			if err != nil {
+				t.Fatalf("DirStats() error: %v", err)
+			}
+			if got.FileCount != 4 || got.DirCount != 3 || got.TotalBytes != 6 {
+				t.Fatalf("DirStats() returned %+v", got)
+			}
+			if got.LatestModTime.Before(start) {
+				t.Fatalf("DirStats() returned LatestModTime %v, want after %v", got.LatestModTime, start)
+			}
+		})
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	files := map[string]string{
+		"a":         "12345",
+		"dir/b":     "123",
+		"dir/c":     "",
+		"dir/sub/d": "1234567890",
+		"other/e":   "12",
+	}
+	mem := NewMemFSFromStrings(files)
+	osFS := OsFS(t.TempDir())
+	for name, s := range files {
+		if err := WriteString(osFS, name, s); err != nil {
+			t.Fatalf("WriteString() error: %v", err)
+		}
+	}
+
+	for name, fs := range map[string]FS{"MemFS": mem, "OsFS": osFS} {
+		for dir, want := range map[string]int64{".": 20, "dir": 13, "dir/sub": 10, "other": 2} {
+			got, err := DiskUsage(fs, dir)
+			if err != nil {
+				t.Fatalf("%s: DiskUsage(%s) error: %v", name, dir, err)
+			}
+			if got != want {
+				t.Fatalf("%s: DiskUsage(%s) returned %d, want %d", name, dir, got, want)
+			}
+		}
+	}
+
+	for _, dir := range []string{"a", "missing"} {
+		if _, err := mem.DiskUsage(dir); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("DiskUsage(%s) returned %v, want %v", dir, err, ErrNotFound)
+		}
+	}
+}
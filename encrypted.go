@@ -0,0 +1,245 @@
+package simplefs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrAuthentication = fmt.Errorf("message authentication failed")
+
+const (
+	// encryptedChunkSize is the maximum number of plain bytes sealed per chunk.
+	encryptedChunkSize = 64 * 1024
+	// encryptedNoncePrefixSize is the size of the random per-frame nonce
+	// prefix. The remaining bytes of the GCM nonce hold the chunk counter.
+	encryptedNoncePrefixSize = 8
+	// encryptedFinalFlag marks the last chunk of a frame in the length prefix.
+	encryptedFinalFlag = 1 << 31
+)
+
+// Encrypted returns an FS that transparently encrypts file contents in fs
+// using AES-GCM. The key must be 16, 24 or 32 bytes long to select AES-128,
+// AES-192 or AES-256.
+//
+// Files are stored as a sequence of frames, one per call to Create or Append.
+// A frame starts with a random nonce prefix followed by length-prefixed
+// chunks, each sealing up to 64 KiB of data, so files can be written and read
+// as streams without buffering them entirely. The last chunk of every frame
+// is flagged as such, so truncated or tampered frames, as well as data
+// written with a different key, fail to read with ErrAuthentication. Note
+// that removing whole trailing frames cannot be detected.
+func Encrypted(fs FS, key []byte) (FS, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedFS{fs: fs, aead: aead}, nil
+}
+
+type encryptedFS struct {
+	fs   FS
+	aead cipher.AEAD
+}
+
+func (fs *encryptedFS) Unwrap() FS {
+	return fs.fs
+}
+
+func (fs *encryptedFS) Open(name string) (File, error) {
+	f, err := fs.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if isDirFile(f) {
+		return f, nil
+	}
+	return &encryptedFile{f: f, aead: fs.aead}, nil
+}
+
+func (fs *encryptedFS) ReadDir(name string) ([]DirEntry, error) {
+	return fs.fs.ReadDir(name)
+}
+
+func (fs *encryptedFS) Create(name string) (io.WriteCloser, error) {
+	w, err := fs.fs.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptedWriter(w, fs.aead)
+}
+
+func (fs *encryptedFS) Append(name string) (io.WriteCloser, error) {
+	w, err := fs.fs.Append(name)
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptedWriter(w, fs.aead)
+}
+
+type encryptedWriter struct {
+	w       io.WriteCloser
+	aead    cipher.AEAD
+	nonce   []byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+func newEncryptedWriter(w io.WriteCloser, aead cipher.AEAD) (*encryptedWriter, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce[:encryptedNoncePrefixSize]); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	if _, err := w.Write(nonce[:encryptedNoncePrefixSize]); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	return &encryptedWriter{w: w, aead: aead, nonce: nonce, buf: make([]byte, 0, encryptedChunkSize)}, nil
+}
+
+func (w *encryptedWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	var n int
+	for len(p) > 0 {
+		if len(w.buf) == encryptedChunkSize {
+			if err := w.seal(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(w.buf[len(w.buf):encryptedChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+func (w *encryptedWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.seal(true)
+	if closeErr := w.w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// seal encrypts and writes the buffered chunk.
+func (w *encryptedWriter) seal(final bool) error {
+	binary.BigEndian.PutUint32(w.nonce[encryptedNoncePrefixSize:], w.counter)
+	w.counter++
+	if w.counter == 0 {
+		return fmt.Errorf("encrypted frame exceeds maximum number of chunks")
+	}
+	header := make([]byte, 4)
+	sealed := w.aead.Seal(nil, w.nonce, w.buf, encryptedChunkAD(final))
+	length := uint32(len(sealed))
+	if final {
+		length |= encryptedFinalFlag
+	}
+	binary.BigEndian.PutUint32(header, length)
+	w.buf = w.buf[:0]
+	if _, err := w.w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+func encryptedChunkAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+type encryptedFile struct {
+	f       File
+	aead    cipher.AEAD
+	nonce   []byte // nil when positioned at the start of a frame
+	counter uint32
+	final   bool // whether the last opened chunk ended its frame
+	plain   []byte
+	sealed  []byte
+}
+
+func (f *encryptedFile) Read(p []byte) (int, error) {
+	for len(f.plain) == 0 {
+		if err := f.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, f.plain)
+	f.plain = f.plain[n:]
+	return n, nil
+}
+
+// next reads and opens the next chunk, starting a new frame if needed.
+func (f *encryptedFile) next() error {
+	if f.nonce == nil || f.final {
+		nonce := make([]byte, f.aead.NonceSize())
+		if _, err := io.ReadFull(f.f, nonce[:encryptedNoncePrefixSize]); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return ErrAuthentication
+			}
+			return err // io.EOF at a frame boundary is the end of the file
+		}
+		f.nonce, f.counter, f.final = nonce, 0, false
+	}
+
+	var header [4]byte
+	if _, err := io.ReadFull(f.f, header[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrAuthentication
+		}
+		return err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	final := length&encryptedFinalFlag != 0
+	length &^= encryptedFinalFlag
+	if length > encryptedChunkSize+uint32(f.aead.Overhead()) {
+		return ErrAuthentication
+	}
+	if cap(f.sealed) < int(length) {
+		f.sealed = make([]byte, length)
+	}
+	f.sealed = f.sealed[:length]
+	if _, err := io.ReadFull(f.f, f.sealed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrAuthentication
+		}
+		return err
+	}
+
+	binary.BigEndian.PutUint32(f.nonce[encryptedNoncePrefixSize:], f.counter)
+	f.counter++
+	plain, err := f.aead.Open(f.sealed[:0], f.nonce, f.sealed, encryptedChunkAD(final))
+	if err != nil {
+		return ErrAuthentication
+	}
+	f.plain, f.final = plain, final
+	return nil
+}
+
+func (f *encryptedFile) Close() error {
+	return f.f.Close()
+}
+
+func (f *encryptedFile) ReadDir(n int) ([]DirEntry, error) {
+	return f.f.ReadDir(n)
+}
@@ -0,0 +1,101 @@
+package simplefs
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestEncrypted(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	mem := &MemFS{}
+	fs, err := Encrypted(mem, key)
+	if err != nil {
+		t.Fatalf("Encrypted() error: %v", err)
+	}
+
+	plain := make([]byte, 3*encryptedChunkSize+100)
+	for i := range plain {
+		plain[i] = byte(i)
+	}
+	if err := WriteFile(fs, "file", plain); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	w, err := fs.Append("file")
+	if err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+	_, _ = w.Write([]byte("appended"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	want := append(plain, "appended"...)
+
+	got, err := ReadFile(fs, "file")
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("ReadFile() returned %d bytes, want %d bytes", len(got), len(want))
+	}
+	stored, _ := ReadFile(mem, "file")
+	if bytes.Contains(stored, []byte("appended")) {
+		t.Fatalf("Backing FS contains plain text")
+	}
+
+	t.Run("Wrong key", func(t *testing.T) {
+		other, _ := Encrypted(mem, bytes.Repeat([]byte{8}, 32))
+		if _, err := ReadFile(other, "file"); !errors.Is(err, ErrAuthentication) {
+			t.Fatalf("ReadFile() returned %v, want ErrAuthentication", err)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		tampered := append([]byte(nil), stored...)
+		tampered[len(tampered)/2] ^= 1
+		mem.SetBytes("tampered", tampered)
+		if _, err := ReadFile(fs, "tampered"); !errors.Is(err, ErrAuthentication) {
+			t.Fatalf("ReadFile() returned %v, want ErrAuthentication", err)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		for _, n := range []int{len(stored) - 1, len(stored) - 30, encryptedNoncePrefixSize + 2, 3} {
+			mem.SetBytes("truncated", stored[:n])
+			if _, err := ReadFile(fs, "truncated"); !errors.Is(err, ErrAuthentication) {
+				t.Fatalf("ReadFile() of %d bytes returned %v, want ErrAuthentication", n, err)
+			}
+		}
+	})
+
+	t.Run("Conformance", func(t *testing.T) {
+		fs, _ := Encrypted(&MemFS{}, key)
+		if msg := RunFileSystemTest(fs); msg != "" {
+			t.Fatal(msg)
+		}
+	})
+}
+
+func TestEncryptedWriteAfterClose(t *testing.T) {
+	fs, err := Encrypted(&MemFS{}, bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("Encrypted() error: %v", err)
+	}
+	w, err := fs.Create("file")
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if _, err := w.Write([]byte("data")); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if n, err := w.Write([]byte("more")); n != 0 || !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Write() after Close() returned %d, %v, want 0, os.ErrClosed", n, err)
+	}
+	if got, err := ReadFile(fs, "file"); err != nil || string(got) != "data" {
+		t.Fatalf("ReadFile() returned %q, %v, want %q", got, err, "data")
+	}
+}
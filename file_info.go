@@ -5,10 +5,15 @@ import (
 	"time"
 )
 
+// defaultFileMode is the mode of files created without an explicit mode.
+const defaultFileMode os.FileMode = 0666
+
 type fileInfo struct {
-	name  string
-	size  int64
-	isDir bool
+	name    string
+	size    int64
+	isDir   bool
+	mode    os.FileMode
+	modTime time.Time
 }
 
 func (info *fileInfo) Name() string {
@@ -20,19 +25,21 @@ func (info *fileInfo) Size() int64 {
 }
 
 func (info *fileInfo) Mode() os.FileMode {
-	panic("Not implemented")
+	return info.mode
 }
 
+// ModTime returns the modification time, or the zero time if it is unknown.
 func (info *fileInfo) ModTime() time.Time {
-	panic("Not implemented")
+	return info.modTime
 }
 
 func (info *fileInfo) IsDir() bool {
 	return info.isDir
 }
 
+// Sys is a stub: there is no underlying data source, so it returns nil.
 func (info *fileInfo) Sys() interface{} {
-	panic("Not implemented")
+	return nil
 }
 
 func (info *fileInfo) String() string {
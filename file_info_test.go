@@ -0,0 +1,57 @@
+package simplefs
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestFileInfoStubsDoNotPanic(t *testing.T) {
+	info := &fileInfo{name: "file", size: 3}
+	if mode := info.Mode(); mode != 0 {
+		t.Fatalf("Mode() returned %v", mode)
+	}
+	if modTime := info.ModTime(); !modTime.IsZero() {
+		t.Fatalf("ModTime() returned %v", modTime)
+	}
+	if sys := info.Sys(); sys != nil {
+		t.Fatalf("Sys() returned %v", sys)
+	}
+}
+
+func TestStatNotImplemented(t *testing.T) {
+	if _, err := Stat(Compressed(&MemFS{}), "file"); !errors.Is(err, ErrNotImplemented) {
+		t.Fatalf("Stat() returned %v, want ErrNotImplemented", err)
+	}
+}
+
+func TestDirEntryTypeAndInfo(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		t.Run(name, func(t *testing.T) {
+			if err := WriteString(fs, "file", "12345"); err != nil {
+				t.Fatalf("WriteString() error: %v", err)
+			}
+			if err := WriteString(fs, "dir/file", ""); err != nil {
+				t.Fatalf("WriteString() error: %v", err)
+			}
+			entries, err := fs.ReadDir(".")
+			if err != nil || len(entries) != 2 {
+				t.Fatalf("ReadDir() returned %v, %v", entries, err)
+			}
+			dir, file := entries[0], entries[1]
+			if dir.Type() != os.ModeDir || file.Type() != 0 {
+				t.Fatalf("Type() returned %v and %v", dir.Type(), file.Type())
+			}
+			info, err := file.Info()
+			if err != nil {
+				t.Fatalf("Info() error: %v", err)
+			}
+			if info.Name() != "file" || info.Size() != 5 || info.IsDir() {
+				t.Fatalf("Info() returned name %s, size %d, isDir %v", info.Name(), info.Size(), info.IsDir())
+			}
+			if info, err := dir.Info(); err != nil || !info.IsDir() {
+				t.Fatalf("Info() of directory returned %v, %v", info, err)
+			}
+		})
+	}
+}
@@ -6,10 +6,54 @@ package simplefs
 import (
 	"fmt"
 	"io"
+	"os"
 )
 
+// ErrNotFound is returned when a path does not exist. The errors returned by
+// Open, Stat and ReadDir wrap it in an *FSError that names the path, so it
+// must be tested for with errors.Is(err, ErrNotFound) rather than ==.
 var ErrNotFound = fmt.Errorf("not found")
+var ErrTooManyLinks = fmt.Errorf("too many levels of symbolic links")
+var ErrNotImplemented = fmt.Errorf("not implemented")
+var ErrExist = fmt.Errorf("already exists")
 
+// ErrIsDir is returned when reading from a directory, and ErrNotDir when
+// listing a file as a directory. They are wrapped in an *FSError, so test
+// for them with errors.Is.
+var ErrIsDir = fmt.Errorf("is a directory")
+var ErrNotDir = fmt.Errorf("not a directory")
+
+// FSError records an error together with the operation and path that caused
+// it, like os.PathError. It unwraps to the underlying error, so errors.Is
+// still matches sentinels such as ErrNotFound.
+type FSError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (err *FSError) Error() string {
+	return err.Op + " " + err.Path + ": " + err.Err.Error()
+}
+
+func (err *FSError) Unwrap() error {
+	return err.Err
+}
+
+// notFound returns an *FSError wrapping ErrNotFound.
+func notFound(op, name string) error {
+	return &FSError{Op: op, Path: name, Err: ErrNotFound}
+}
+
+// FS is a file system of slash-separated paths. The root directory is named
+// "." and may also be given as "", so ReadDir(".") and ReadDir("") both
+// list the top-level entries. All methods clean the names they are given
+// with CleanPath, so "a//b", "a/./b" and "a/b/" name the same file.
+//
+// ReadDir, and File.ReadDir on a directory, return entries sorted by name in
+// every implementation, so that code does not behave differently depending
+// on the order of the underlying storage. ReadDir returns all entries at
+// once; see File for paging through a directory.
 type FS interface {
 	Open(name string) (File, error)
 	ReadDir(name string) ([]DirEntry, error)
@@ -17,6 +61,252 @@ type FS interface {
 	Append(name string) (io.WriteCloser, error)
 }
 
+// Stater is implemented by file systems that can describe a file without
+// opening it.
+type Stater interface {
+	Stat(name string) (os.FileInfo, error)
+}
+
+// Stat returns a FileInfo describing the named file. It returns an error
+// wrapping ErrNotImplemented if fs does not implement Stater.
+func Stat(fs FS, name string) (os.FileInfo, error) {
+	if s, ok := fs.(Stater); ok {
+		return s.Stat(name)
+	}
+	return nil, fmt.Errorf("cannot stat '%s'. %T does not implement Stater: %w", name, fs, ErrNotImplemented)
+}
+
+// Lstater is implemented by file systems that can describe a symbolic link
+// itself rather than the file it points to.
+type Lstater interface {
+	Lstat(name string) (os.FileInfo, error)
+}
+
+// Lstat returns a FileInfo describing the named file without following a
+// symbolic link in the last element of name. The mode of a link includes
+// os.ModeSymlink. Wrappers that pass names through unchanged, such as Logged,
+// forward Lstat to the FS they wrap. It returns an error wrapping
+// ErrNotImplemented if fs does not implement Lstater, rather than following
+// links with Stat.
+func Lstat(fs FS, name string) (os.FileInfo, error) {
+	if s, ok := fs.(Lstater); ok {
+		return s.Lstat(name)
+	}
+	return nil, fmt.Errorf("cannot lstat '%s'. %T does not implement Lstater: %w", name, fs, ErrNotImplemented)
+}
+
+// ModeCreator is implemented by file systems that can create files with a
+// specific mode. Files created with Create get mode 0666.
+type ModeCreator interface {
+	CreateMode(name string, mode os.FileMode) (io.WriteCloser, error)
+}
+
+// CreateMode creates the named file with the given mode. It returns an error
+// wrapping ErrNotImplemented if fs does not implement ModeCreator.
+func CreateMode(fs FS, name string, mode os.FileMode) (io.WriteCloser, error) {
+	if c, ok := fs.(ModeCreator); ok {
+		return c.CreateMode(name, mode)
+	}
+	return nil, fmt.Errorf("cannot create '%s' with mode %v. %T does not implement ModeCreator: %w", name, mode, fs, ErrNotImplemented)
+}
+
+// ExclCreator is implemented by file systems that can create a file only if
+// it does not already exist, atomically with respect to other creators.
+type ExclCreator interface {
+	CreateExcl(name string) (io.WriteCloser, error)
+}
+
+// CreateExcl creates the named file, or returns an error wrapping ErrExist if
+// the path already exists. This makes it suitable for acquiring lock files.
+// If fs does not implement ExclCreator it falls back to OpenFile with
+// os.O_CREATE|os.O_EXCL, and otherwise returns an error wrapping
+// ErrNotImplemented.
+func CreateExcl(fs FS, name string) (io.WriteCloser, error) {
+	if c, ok := fs.(ExclCreator); ok {
+		return c.CreateExcl(name)
+	}
+	if _, ok := fs.(FileOpener); ok {
+		f, err := OpenFile(fs, name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err != nil {
+			return nil, err
+		}
+		if w, ok := f.(io.WriteCloser); ok {
+			return w, nil
+		}
+		_ = f.Close()
+	}
+	return nil, fmt.Errorf("cannot create '%s' exclusively. %T does not implement ExclCreator: %w", name, fs, ErrNotImplemented)
+}
+
+// SparseCreator is implemented by file systems that can create a file of a
+// given size, reading as zeros, without writing the data.
+type SparseCreator interface {
+	CreateSparse(name string, size int64) error
+}
+
+// CreateSparse creates (or truncates) the named file with the given size,
+// reading as zeros. If fs does not implement SparseCreator the zeros are
+// written out through Create.
+func CreateSparse(fs FS, name string, size int64) error {
+	if c, ok := fs.(SparseCreator); ok {
+		return c.CreateSparse(name, size)
+	}
+	w, err := fs.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(w, zeroReader{}, size); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Toucher is implemented by file systems that can update a file's
+// modification time without rewriting it.
+type Toucher interface {
+	Touch(name string) error
+}
+
+// Touch creates the named file empty if it does not exist, and otherwise sets
+// its modification time to the current time without changing its contents.
+// It returns an error wrapping ErrNotImplemented if fs does not implement
+// Toucher.
+func Touch(fs FS, name string) error {
+	if t, ok := fs.(Toucher); ok {
+		return t.Touch(name)
+	}
+	return fmt.Errorf("cannot touch '%s'. %T does not implement Toucher: %w", name, fs, ErrNotImplemented)
+}
+
+// Syncer is implemented by writers that can commit written data to durable
+// storage before they are closed. The writers returned by OsFS implement it
+// with fsync, so that data is on disk once Sync returns; Close alone does
+// not guarantee that. MemFS writers implement it as a no-op, as a MemFS is
+// never durable beyond the lifetime of the process.
+type Syncer interface {
+	Sync() error
+}
+
+// Sync commits the data written to w to durable storage. It returns an error
+// wrapping ErrNotImplemented if w does not implement Syncer.
+func Sync(w io.Writer) error {
+	if s, ok := w.(Syncer); ok {
+		return s.Sync()
+	}
+	return fmt.Errorf("cannot sync. %T does not implement Syncer: %w", w, ErrNotImplemented)
+}
+
+// DirMaker is implemented by file systems that can create directories
+// explicitly, rather than only as the parents of files.
+type DirMaker interface {
+	MkdirAll(name string) error
+}
+
+// MkdirAll creates the named directory along with any missing parents. It
+// does nothing if the directory already exists, and returns an error if the
+// path or one of its parents is a file. It returns an error wrapping
+// ErrNotImplemented if fs does not implement DirMaker.
+func MkdirAll(fs FS, name string) error {
+	if m, ok := fs.(DirMaker); ok {
+		return m.MkdirAll(name)
+	}
+	return fmt.Errorf("cannot create directory '%s'. %T does not implement DirMaker: %w", name, fs, ErrNotImplemented)
+}
+
+// Remover is implemented by file systems that support removing files.
+// Remove removes a file or an empty directory and returns ErrNotFound if it
+// does not exist. RemoveAll removes a path and everything it contains, and
+// returns nil if it does not exist.
+type Remover interface {
+	Remove(name string) error
+	RemoveAll(name string) error
+}
+
+// Remove removes the named file or empty directory. It returns an error
+// wrapping ErrNotImplemented if fs does not implement Remover.
+func Remove(fs FS, name string) error {
+	if r, ok := fs.(Remover); ok {
+		return r.Remove(name)
+	}
+	return fmt.Errorf("cannot remove '%s'. %T does not implement Remover: %w", name, fs, ErrNotImplemented)
+}
+
+// RemoveAll removes the named path and everything it contains. It returns an
+// error wrapping ErrNotImplemented if fs does not implement Remover.
+func RemoveAll(fs FS, name string) error {
+	if r, ok := fs.(Remover); ok {
+		return r.RemoveAll(name)
+	}
+	return fmt.Errorf("cannot remove '%s'. %T does not implement Remover: %w", name, fs, ErrNotImplemented)
+}
+
+// Renamer is implemented by file systems that can rename files and
+// directories. Renaming a file onto an existing file replaces it.
+type Renamer interface {
+	Rename(oldName, newName string) error
+}
+
+// Rename renames oldName to newName. It returns an error wrapping
+// ErrNotImplemented if fs does not implement Renamer.
+func Rename(fs FS, oldName, newName string) error {
+	if r, ok := fs.(Renamer); ok {
+		return r.Rename(oldName, newName)
+	}
+	return fmt.Errorf("cannot rename '%s'. %T does not implement Renamer: %w", oldName, fs, ErrNotImplemented)
+}
+
+// FileOpener is implemented by file systems that can open files with the
+// flags of os.OpenFile: os.O_RDONLY, os.O_WRONLY or os.O_RDWR, combined with
+// os.O_APPEND, os.O_CREATE, os.O_EXCL and os.O_TRUNC. Opening an existing
+// file with os.O_CREATE|os.O_EXCL returns ErrExist, and perm is the mode of
+// files created by os.O_CREATE. Files opened for writing implement
+// io.Writer and io.WriterAt.
+type FileOpener interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+}
+
+// OpenFile opens the named file with the given flags. It returns an error
+// wrapping ErrNotImplemented if fs does not implement FileOpener.
+func OpenFile(fs FS, name string, flag int, perm os.FileMode) (File, error) {
+	if o, ok := fs.(FileOpener); ok {
+		return o.OpenFile(name, flag, perm)
+	}
+	return nil, fmt.Errorf("cannot open '%s'. %T does not implement FileOpener: %w", name, fs, ErrNotImplemented)
+}
+
+// Symlinker is implemented by file systems that support symbolic links.
+type Symlinker interface {
+	Symlink(target, linkName string) error
+	Readlink(name string) (string, error)
+}
+
+// Wrapper is implemented by file systems that decorate another FS.
+type Wrapper interface {
+	Unwrap() FS
+}
+
+// Unwrap returns the FS wrapped by fs, or nil if fs does not implement
+// Wrapper. Like errors.Unwrap, it can be called in a loop to reach the
+// underlying backend.
+func Unwrap(fs FS) FS {
+	if w, ok := fs.(Wrapper); ok {
+		return w.Unwrap()
+	}
+	return nil
+}
+
+// File is an open file or directory.
+//
+// ReadDir lists an open directory, and is the way to page through a directory
+// at the FS level: Open the directory and call ReadDir(n) repeatedly. It
+// follows the semantics of os.File.ReadDir. If n > 0, it returns at most n
+// entries, continuing where the previous call stopped, and returns io.EOF
+// with no entries once the directory has been read. If n <= 0, it returns
+// all remaining entries with a nil error. Entries are sorted by name, which
+// means the implementations may read the whole directory on the first call;
+// use ReadDirIter to list a huge directory without holding all of its
+// entries in memory. ReadDir returns an error on a file.
 type File interface {
 	Read([]byte) (int, error)
 	Close() error
@@ -31,11 +321,23 @@ type DirEntry interface {
 
 	// IsDir reports whether the entry describes a directory.
 	IsDir() bool
+
+	// Type returns the type bits for the entry.
+	// The type bits are a subset of the usual FileMode bits, those returned by the FileMode.Type method.
+	Type() os.FileMode
+
+	// Info returns the FileInfo for the file or subdirectory described by the entry.
+	Info() (os.FileInfo, error)
 }
 
 type dirEntry struct {
 	name  string
 	isDir bool
+	info  os.FileInfo
+}
+
+func newDirEntry(info os.FileInfo) *dirEntry {
+	return &dirEntry{name: info.Name(), isDir: info.IsDir(), info: info}
 }
 
 func (entry *dirEntry) Name() string {
@@ -46,6 +348,27 @@ func (entry *dirEntry) IsDir() bool {
 	return entry.isDir
 }
 
+func (entry *dirEntry) Type() os.FileMode {
+	if entry.info != nil {
+		return entry.info.Mode().Type()
+	}
+	if entry.isDir {
+		return os.ModeDir
+	}
+	return 0
+}
+
+func (entry *dirEntry) Info() (os.FileInfo, error) {
+	if entry.info != nil {
+		return entry.info, nil
+	}
+	info := &fileInfo{name: entry.name, isDir: entry.isDir, mode: defaultFileMode}
+	if entry.isDir {
+		info.mode = os.ModeDir | 0777
+	}
+	return info, nil
+}
+
 func (entry *dirEntry) String() string {
 	if entry == nil {
 		return "<nil>"
@@ -0,0 +1,245 @@
+package simplefs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestFSError(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		_, openErr := fs.Open("dir/missing")
+		_, readDirErr := fs.ReadDir("dir/missing")
+		_, statErr := Stat(fs, "dir/missing")
+		for op, err := range map[string]error{"open": openErr, "readdir": readDirErr, "stat": statErr} {
+			var fsErr *FSError
+			if !errors.As(err, &fsErr) {
+				t.Fatalf("%s: %s returned %T, want *FSError", name, op, err)
+			}
+			if fsErr.Op != op || fsErr.Path != "dir/missing" || !errors.Is(err, ErrNotFound) {
+				t.Fatalf("%s: %s returned %#v", name, op, fsErr)
+			}
+			if want := op + " dir/missing: not found"; err.Error() != want {
+				t.Fatalf("%s: Error() returned %q, want %q", name, err.Error(), want)
+			}
+		}
+	}
+}
+
+func TestMemFSNotFoundErrors(t *testing.T) {
+	fs := &MemFS{}
+	_, bytesErr := fs.Bytes("dir/missing")
+	_, listFilesErr := fs.ListFiles("dir/missing")
+	_, listDirsErr := fs.ListDirs("dir/missing")
+	_, readlinkErr := fs.Readlink("dir/missing")
+	_, diskUsageErr := fs.DiskUsage("dir/missing")
+	errs := map[string]error{
+		"read":      bytesErr,
+		"remove":    fs.Remove("dir/missing"),
+		"listfiles": listFilesErr,
+		"listdirs":  listDirsErr,
+		"readlink":  readlinkErr,
+		"eachfile":  fs.EachFile("dir/missing", func(string) error { return nil }),
+		"diskusage": diskUsageErr,
+	}
+	for op, err := range errs {
+		var fsErr *FSError
+		if !errors.As(err, &fsErr) || fsErr.Op != op || fsErr.Path != "dir/missing" || !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s returned %#v, want an *FSError wrapping ErrNotFound", op, err)
+		}
+	}
+}
+
+func TestOsFSNotFoundErrors(t *testing.T) {
+	fs := OsFS(t.TempDir())
+	if err := MkdirAll(fs, "dir"); err != nil {
+		t.Fatalf("MkdirAll() error: %v", err)
+	}
+	dir, err := fs.Open("dir")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer func() { _ = dir.Close() }()
+	if err := Remove(fs, "dir"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	_, listFilesErr := fs.(interface {
+		ListFiles(string) ([]string, error)
+	}).ListFiles("dir/missing")
+	_, listDirsErr := fs.(interface {
+		ListDirs(string) ([]string, error)
+	}).ListDirs("dir/missing")
+	_, readlinkErr := fs.(Symlinker).Readlink("dir/missing")
+	_, readDirErr := dir.ReadDir(-1)
+	errs := map[string]error{
+		"remove":    Remove(fs, "dir/missing"),
+		"listfiles": listFilesErr,
+		"listdirs":  listDirsErr,
+		"readlink":  readlinkErr,
+		"readdir":   readDirErr,
+	}
+	for op, err := range errs {
+		var fsErr *FSError
+		if !errors.As(err, &fsErr) || fsErr.Op != op || !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s returned %#v, want an *FSError wrapping ErrNotFound", op, err)
+		}
+	}
+}
+
+func TestFileReadDirPaging(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		for i := 0; i < 5; i++ {
+			w, err := fs.Create(fmt.Sprintf("dir/file%d", i))
+			if err != nil {
+				t.Fatalf("%s: Create() error: %v", name, err)
+			}
+			_ = w.Close()
+		}
+		dir, err := fs.Open("dir")
+		if err != nil {
+			t.Fatalf("%s: Open() error: %v", name, err)
+		}
+
+		readDir := func(n int, want []string, wantErr error) {
+			entries, err := dir.ReadDir(n)
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.Name())
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) || err != wantErr {
+				t.Fatalf("%s: ReadDir(%d) returned %v, %v, want %v, %v", name, n, got, err, want, wantErr)
+			}
+		}
+		readDir(2, []string{"file0", "file1"}, nil)
+		readDir(2, []string{"file2", "file3"}, nil)
+		readDir(0, []string{"file4"}, nil)
+		readDir(1, nil, io.EOF)
+		readDir(-1, nil, nil)
+		_ = dir.Close()
+	}
+}
+
+func TestCreateDirectory(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		if err := WriteString(fs, "a/b", "b"); err != nil {
+			t.Fatalf("%s: WriteString() error: %v", name, err)
+		}
+		if _, err := fs.Create("a"); !errors.Is(err, ErrIsDir) {
+			t.Fatalf("%s: Create() on a directory returned %v, want ErrIsDir", name, err)
+		}
+		if _, err := fs.Append("a"); !errors.Is(err, ErrIsDir) {
+			t.Fatalf("%s: Append() on a directory returned %v, want ErrIsDir", name, err)
+		}
+		if b, err := ReadFile(fs, "a/b"); err != nil || string(b) != "b" {
+			t.Fatalf("%s: ReadFile() after Create() on its directory returned %q, %v", name, b, err)
+		}
+	}
+
+	// A directory created while a writer is open is not replaced on Close.
+	fs := &MemFS{}
+	w, _ := fs.Create("a")
+	fs.SetString("a/b", "b")
+	if err := w.Close(); !errors.Is(err, ErrIsDir) {
+		t.Fatalf("Close() over a directory returned %v, want ErrIsDir", err)
+	}
+	if b, err := fs.Bytes("a/b"); err != nil || string(b) != "b" {
+		t.Fatalf("Bytes() after Close() over its directory returned %q, %v", b, err)
+	}
+}
+
+func TestWriterCloseTwice(t *testing.T) {
+	for name, fs := range map[string]FS{
+		"MemFS":         &MemFS{},
+		"OsFS":          OsFS(t.TempDir()),
+		"OsFS buffered": OsFS(t.TempDir(), WithWriteBuffer(64)),
+	} {
+		for op, open := range map[string]func(string) (io.WriteCloser, error){"Create": fs.Create, "Append": fs.Append} {
+			w, err := open(op)
+			if err != nil {
+				t.Fatalf("%s: %s() error: %v", name, op, err)
+			}
+			_, _ = w.Write([]byte("data"))
+			if err := w.Close(); err != nil {
+				t.Fatalf("%s: %s: Close() error: %v", name, op, err)
+			}
+			if err := w.Close(); !errors.Is(err, os.ErrClosed) {
+				t.Fatalf("%s: %s: second Close() returned %v, want os.ErrClosed", name, op, err)
+			}
+			if n, err := w.Write([]byte("more")); n != 0 || !errors.Is(err, os.ErrClosed) {
+				t.Fatalf("%s: %s: Write() after Close() returned %d, %v, want 0, os.ErrClosed", name, op, n, err)
+			}
+			_ = w.Close()
+			if b, err := ReadFile(fs, op); err != nil || string(b) != "data" {
+				t.Fatalf("%s: %s: file contains %q, %v, want %q", name, op, b, err, "data")
+			}
+		}
+	}
+}
+
+func TestReadFileNames(t *testing.T) {
+	type fileNamesReader interface {
+		ReadFileNames(dir string) ([]string, error)
+	}
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		for _, file := range []string{"dir/b", "dir/a", "dir/sub/c", "top"} {
+			if err := WriteString(fs, file, file); err != nil {
+				t.Fatalf("%s: WriteString() error: %v", name, err)
+			}
+		}
+		r := fs.(fileNamesReader)
+		for dir, want := range map[string]string{".": "[top]", "dir": "[a b]", "dir/sub": "[c]"} {
+			got, err := r.ReadFileNames(dir)
+			if err != nil || fmt.Sprint(got) != want {
+				t.Fatalf("%s: ReadFileNames(%s) returned %v, %v, want %s", name, dir, got, err, want)
+			}
+		}
+		if _, err := r.ReadFileNames("missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: ReadFileNames(missing) returned %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestLstat(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		if err := WriteString(fs, "dir/file", "contents"); err != nil {
+			t.Fatalf("%s: WriteString() error: %v", name, err)
+		}
+		if err := fs.(Symlinker).Symlink("dir/file", "link"); err != nil {
+			t.Fatalf("%s: Symlink() error: %v", name, err)
+		}
+
+		info, err := Lstat(fs, "link")
+		if err != nil {
+			t.Fatalf("%s: Lstat() error: %v", name, err)
+		}
+		if info.Name() != "link" || info.Mode()&os.ModeSymlink == 0 {
+			t.Fatalf("%s: Lstat() returned %s with mode %v, want a symbolic link", name, info.Name(), info.Mode())
+		}
+		if info, err := Stat(fs, "link"); err != nil || info.Mode()&os.ModeSymlink != 0 || info.Size() != 8 {
+			t.Fatalf("%s: Stat() returned %v, %v, want the target", name, info, err)
+		}
+		if info, err := Lstat(fs, "dir/file"); err != nil || !info.Mode().IsRegular() {
+			t.Fatalf("%s: Lstat() of a file returned %v, %v", name, info, err)
+		}
+		if _, err := Lstat(fs, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: Lstat(missing) returned %v, want ErrNotFound", name, err)
+		}
+	}
+
+	// Lstat reaches the Lstater wrapped by fs.
+	mem := NewMemFSFromStrings(map[string]string{"file": "x"})
+	if err := mem.Symlink("file", "link"); err != nil {
+		t.Fatalf("Symlink() error: %v", err)
+	}
+	fs := Logged(mem, func(string, ...interface{}) {})
+	if info, err := Lstat(fs, "link"); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("Lstat() through Logged returned %v, %v, want the link", info, err)
+	}
+
+	// Without an Lstater, Lstat does not fall back to Stat.
+	if _, err := Lstat(unseekableFS{mem}, "file"); !errors.Is(err, ErrNotImplemented) {
+		t.Fatalf("Lstat() without Lstater returned %v, want ErrNotImplemented", err)
+	}
+}
@@ -0,0 +1,91 @@
+package simplefs
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Glob returns the sorted paths of all files in fs that match pattern, using
+// the syntax of path.Match. A pattern containing a slash is matched against
+// the full path, so "dir/*.go" only matches files directly in dir. A pattern
+// without a slash is matched against the base name of every file in the
+// tree, so "*.tmp" matches temporary files in any directory. Directories are
+// never returned. The only possible error for a malformed pattern is
+// path.ErrBadPattern.
+func Glob(fs FS, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	baseOnly := !strings.Contains(pattern, "/")
+	var names []string
+	err := WalkFiles(fs, ".", func(name string) error {
+		subject := name
+		if baseOnly {
+			subject = path.Base(name)
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// RemoveGlob removes every file matched by pattern as in Glob, and returns the
+// number of files removed. A failure to remove one file does not stop the
+// others from being removed; the errors are joined in the returned error.
+// fs must implement Remover.
+func RemoveGlob(fs FS, pattern string) (int, error) {
+	names, err := Glob(fs, pattern)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	var errs []error
+	for _, name := range names {
+		if err := Remove(fs, name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
+
+// DirMatcher is implemented by file systems that can filter a directory
+// listing at the source, such as a remote backend that lists by prefix, so
+// that entries which do not match are never transferred.
+type DirMatcher interface {
+	ReadDirMatch(dir, pattern string) ([]DirEntry, error)
+}
+
+// ReadDirMatch returns the entries of dir whose names match pattern, using
+// the syntax of path.Match, sorted by name like ReadDir. If fs implements
+// DirMatcher it does the filtering; otherwise the result of ReadDir is
+// filtered. The only possible error for a malformed pattern is
+// path.ErrBadPattern.
+func ReadDirMatch(fs FS, dir, pattern string) ([]DirEntry, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if m, ok := fs.(DirMatcher); ok {
+		return m.ReadDirMatch(dir, pattern)
+	}
+	entries, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var matched []DirEntry
+	for _, entry := range entries {
+		if ok, _ := path.Match(pattern, entry.Name()); ok {
+			matched = append(matched, entry)
+		}
+	}
+	return matched, nil
+}
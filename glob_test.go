@@ -0,0 +1,102 @@
+package simplefs
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{
+		"a.tmp":         "",
+		"b.txt":         "",
+		"dir/c.tmp":     "",
+		"dir/d.txt":     "",
+		"dir/sub/e.tmp": "",
+		"x.tmp/f.txt":   "",
+	})
+	tests := map[string]string{
+		"*.tmp":     "a.tmp,dir/c.tmp,dir/sub/e.tmp",
+		"dir/*.tmp": "dir/c.tmp",
+		"dir/*/*":   "dir/sub/e.tmp",
+		"?.txt":     "b.txt,dir/d.txt,x.tmp/f.txt",
+		"*.none":    "",
+	}
+	for pattern, want := range tests {
+		got, err := Glob(fs, pattern)
+		if err != nil {
+			t.Fatalf("Glob(%s) error: %v", pattern, err)
+		}
+		if strings.Join(got, ",") != want {
+			t.Fatalf("Glob(%s) returned %v, want %s", pattern, got, want)
+		}
+	}
+	if _, err := Glob(fs, "["); err != path.ErrBadPattern {
+		t.Fatalf("Glob([) returned %v, want %v", err, path.ErrBadPattern)
+	}
+}
+
+func TestRemoveGlob(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{"a.tmp": "", "dir/b.tmp": "", "dir/c.txt": ""})
+	n, err := RemoveGlob(fs, "*.tmp")
+	if err != nil {
+		t.Fatalf("RemoveGlob() error: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("RemoveGlob() returned %d, want 2", n)
+	}
+	if got, _ := Glob(fs, "*"); strings.Join(got, ",") != "dir/c.txt" {
+		t.Fatalf("Glob(*) after RemoveGlob() returned %v", got)
+	}
+
+	readOnly := struct{ FS }{fs}
+	if n, err := RemoveGlob(readOnly, "*.txt"); n != 0 || !errors.Is(err, ErrNotImplemented) {
+		t.Fatalf("RemoveGlob() on non-Remover returned %d, %v", n, err)
+	}
+}
+
+// matchCountingFS records the patterns passed to ReadDirMatch.
+type matchCountingFS struct {
+	FS
+	patterns []string
+}
+
+func (fs *matchCountingFS) ReadDirMatch(dir, pattern string) ([]DirEntry, error) {
+	fs.patterns = append(fs.patterns, pattern)
+	return nil, nil
+}
+
+func TestReadDirMatch(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		for _, file := range []string{"dir/a.tmp", "dir/b.txt", "dir/c.tmp", "dir/d.tmp/e.txt"} {
+			if err := WriteString(fs, file, ""); err != nil {
+				t.Fatalf("%s: WriteString() error: %v", name, err)
+			}
+		}
+		for pattern, want := range map[string]string{"*.tmp": "a.tmp,c.tmp,d.tmp", "b.*": "b.txt", "*.go": ""} {
+			entries, err := ReadDirMatch(fs, "dir", pattern)
+			if err != nil {
+				t.Fatalf("%s: ReadDirMatch(%s) error: %v", name, pattern, err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.Name())
+			}
+			if strings.Join(got, ",") != want {
+				t.Fatalf("%s: ReadDirMatch(%s) returned %v, want %s", name, pattern, got, want)
+			}
+		}
+		if _, err := ReadDirMatch(fs, "dir", "["); !errors.Is(err, path.ErrBadPattern) {
+			t.Fatalf("%s: ReadDirMatch() with a bad pattern returned %v, want ErrBadPattern", name, err)
+		}
+		if _, err := ReadDirMatch(fs, "missing", "*"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: ReadDirMatch(missing) returned %v, want ErrNotFound", name, err)
+		}
+	}
+
+	fs := &matchCountingFS{FS: &MemFS{}}
+	if _, err := ReadDirMatch(fs, ".", "*.tmp"); err != nil || strings.Join(fs.patterns, ",") != "*.tmp" {
+		t.Fatalf("ReadDirMatch() passed %v, %v to DirMatcher, want *.tmp", fs.patterns, err)
+	}
+}
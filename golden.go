@@ -0,0 +1,99 @@
+package simplefs
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CompareOrUpdate implements the golden-file workflow for the directory
+// goldenDir on disk. If update is false, it compares every file in fs with
+// the files in goldenDir and returns an error listing each file that is
+// missing, unexpected or different. If update is true, it writes the files of
+// fs into goldenDir, overwriting existing ones and removing files that are not
+// in fs, so that a following comparison succeeds.
+func CompareOrUpdate(fs FS, goldenDir string, update bool) error {
+	golden := OsFS(goldenDir)
+	if update {
+		return updateGolden(fs, golden)
+	}
+
+	got, err := readTree(fs)
+	if err != nil {
+		return err
+	}
+	want, err := readTree(golden)
+	if err != nil {
+		return fmt.Errorf("cannot read golden directory '%s': %w", goldenDir, err)
+	}
+	var diffs []string
+	for name, b := range got {
+		wantB, ok := want[name]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("'%s' is not in the golden directory", name))
+		} else if !bytes.Equal(b, wantB) {
+			diffs = append(diffs, fmt.Sprintf("'%s' differs: got %d bytes %q, want %d bytes %q", name, len(b), abbreviate(b), len(wantB), abbreviate(wantB)))
+		}
+	}
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("'%s' is missing", name))
+		}
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+	sort.Strings(diffs)
+	return fmt.Errorf("files do not match golden directory '%s':\n\t%s", goldenDir, strings.Join(diffs, "\n\t"))
+}
+
+func updateGolden(fs FS, golden FS) error {
+	names := map[string]bool{}
+	err := WalkFiles(fs, ".", func(name string) error {
+		names[name] = true
+		return CopyFile(golden, name, fs, name)
+	})
+	if err != nil {
+		return err
+	}
+	var stale []string
+	err = WalkFiles(golden, ".", func(name string) error {
+		if !names[name] {
+			stale = append(stale, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range stale {
+		if err := Remove(golden, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTree reads the contents of every file in fs, keyed by path.
+func readTree(fs FS) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := WalkFiles(fs, ".", func(name string) error {
+		b, err := ReadFile(fs, name)
+		if err != nil {
+			return err
+		}
+		files[name] = b
+		return nil
+	})
+	return files, err
+}
+
+// abbreviate shortens b for use in an error message.
+func abbreviate(b []byte) []byte {
+	const max = 32
+	if len(b) > max {
+		return append(b[:max:max], "..."...)
+	}
+	return b
+}
@@ -0,0 +1,36 @@
+package simplefs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareOrUpdate(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteString(OsFS(dir), "stale", "old"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+
+	fs := NewMemFSFromStrings(map[string]string{"a": "1", "dir/b": "2"})
+	if err := CompareOrUpdate(fs, dir, false); err == nil {
+		t.Fatalf("CompareOrUpdate() returned nil before update, want error")
+	}
+	if err := CompareOrUpdate(fs, dir, true); err != nil {
+		t.Fatalf("CompareOrUpdate(update) error: %v", err)
+	}
+	if err := CompareOrUpdate(fs, dir, false); err != nil {
+		t.Fatalf("CompareOrUpdate() after update returned %v, want nil", err)
+	}
+
+	fs.SetString("dir/b", "changed")
+	fs.SetString("c", "3")
+	err := CompareOrUpdate(fs, dir, false)
+	if err == nil {
+		t.Fatalf("CompareOrUpdate() returned nil, want error")
+	}
+	for _, want := range []string{"'dir/b' differs", "'c' is not in the golden directory"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("CompareOrUpdate() returned %q, want it to contain %q", err, want)
+		}
+	}
+}
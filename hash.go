@@ -0,0 +1,26 @@
+package simplefs
+
+import (
+	"crypto/sha256"
+	"hash"
+	"io"
+)
+
+// Hash streams the contents of the named file through h and returns the
+// resulting digest. h is not reset first, so a fresh hash should be passed.
+func Hash(fs FS, name string, h hash.Hash) ([]byte, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// SHA256 returns the SHA-256 digest of the contents of the named file.
+func SHA256(fs FS, name string) ([]byte, error) {
+	return Hash(fs, name, sha256.New())
+}
@@ -0,0 +1,33 @@
+package simplefs
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		if err := WriteString(fs, "file", "hello"); err != nil {
+			t.Fatalf("%s: WriteString() error: %v", name, err)
+		}
+		sum, err := SHA256(fs, "file")
+		if err != nil {
+			t.Fatalf("%s: SHA256() error: %v", name, err)
+		}
+		if got, want := hex.EncodeToString(sum), "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"; got != want {
+			t.Fatalf("%s: SHA256() returned %s, want %s", name, got, want)
+		}
+		sum, err = Hash(fs, "file", md5.New())
+		if err != nil {
+			t.Fatalf("%s: Hash() error: %v", name, err)
+		}
+		if got, want := hex.EncodeToString(sum), "5d41402abc4b2a76b9719d911017c592"; got != want {
+			t.Fatalf("%s: Hash(md5) returned %s, want %s", name, got, want)
+		}
+		if _, err := SHA256(fs, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: SHA256(missing) returned %v, want %v", name, err, ErrNotFound)
+		}
+	}
+}
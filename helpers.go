@@ -0,0 +1,106 @@
+package simplefs
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// statFile is implemented by the Files returned from the package's own FS
+// implementations.
+type statFile interface {
+	Stat() (os.FileInfo, error)
+}
+
+// WouldOverwrite reports whether a file already exists at name, i.e. whether
+// a call to Create(name) would replace existing data. It returns an error
+// wrapping ErrIsDir if name is a directory, since a file cannot be created
+// there. If fs does not implement Stater, the file is opened instead.
+func WouldOverwrite(fs FS, name string) (bool, error) {
+	if _, ok := fs.(Stater); !ok {
+		f, err := fs.Open(name)
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		defer func() { _ = f.Close() }()
+		if isDirFile(f) {
+			return false, &FSError{Op: "create", Path: name, Err: ErrIsDir}
+		}
+		return true, nil
+	}
+	info, err := Stat(fs, name)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.IsDir() {
+		return false, &FSError{Op: "create", Path: name, Err: ErrIsDir}
+	}
+	return true, nil
+}
+
+// WriteFile creates (or truncates) the named file and writes b to it. The
+// writer is always closed, and the first error encountered is returned.
+func WriteFile(fs FS, name string, b []byte) error {
+	w, err := fs.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// WriteString is like WriteFile but writes the contents of s.
+func WriteString(fs FS, name, s string) error {
+	return WriteFile(fs, name, []byte(s))
+}
+
+// ReadFile opens the named file and returns its contents. The file is always
+// closed, even if reading fails. Reading a directory returns an error.
+func ReadFile(fs FS, name string) (b []byte, err error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	if s, ok := f.(statFile); ok {
+		if info, err := s.Stat(); err == nil && info.IsDir() {
+			return nil, &FSError{Op: "read", Path: name, Err: ErrIsDir}
+		}
+	}
+	return io.ReadAll(f)
+}
+
+// OpenSeekable opens the named file for reading and seeking. If the File
+// returned by fs already implements io.Seeker it is returned directly.
+// Otherwise the whole file is read into memory to provide seeking, so
+// non-seekable backends incur the cost of buffering the full contents.
+func OpenSeekable(fs FS, name string) (io.ReadSeekCloser, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if rs, ok := f.(io.ReadSeekCloser); ok {
+		return rs, nil
+	}
+	b, err := io.ReadAll(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newBytesFile(name, b), nil
+}
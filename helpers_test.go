@@ -0,0 +1,104 @@
+package simplefs
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestWouldOverwrite(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetString("dir/file", "contents")
+
+	tests := []struct {
+		name    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "dir/file", want: true},
+		{name: "dir/other", want: false},
+		{name: "missing/file", want: false},
+		{name: "dir", wantErr: true},
+	}
+	// Without Stater, WouldOverwrite opens the file instead.
+	withoutStat := struct{ FS }{fs}
+	for name, fs := range map[string]FS{"Stater": fs, "Open": withoutStat} {
+		for _, test := range tests {
+			got, err := WouldOverwrite(fs, test.name)
+			if test.wantErr {
+				if !errors.Is(err, ErrIsDir) {
+					t.Fatalf("%s: WouldOverwrite(%s) returned %v, want ErrIsDir", name, test.name, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: WouldOverwrite(%s) returned error: %v", name, test.name, err)
+			}
+			if got != test.want {
+				t.Fatalf("%s: WouldOverwrite(%s) returned %v, want %v", name, test.name, got, test.want)
+			}
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	fs := &MemFS{}
+	if err := WriteFile(fs, "dir/file", []byte{1, 2, 3}); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if err := WriteString(fs, "dir/text", "hello"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	for name, want := range map[string]string{"dir/file": "\x01\x02\x03", "dir/text": "hello"} {
+		b, err := ReadFile(fs, name)
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error: %v", name, err)
+		}
+		if string(b) != want {
+			t.Fatalf("%s: got contents %q, want %q", name, b, want)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetString("dir/file", "contents")
+
+	b, err := ReadFile(fs, "dir/file")
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if string(b) != "contents" {
+		t.Fatalf("ReadFile() returned %q", b)
+	}
+	if _, err := ReadFile(fs, "dir/missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ReadFile() on missing file returned %v, want ErrNotFound", err)
+	}
+	if _, err := ReadFile(fs, "dir"); err == nil {
+		t.Fatalf("ReadFile() on directory returned nil error")
+	}
+}
+
+func TestOpenSeekable(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetString("file", "0123456789")
+
+	r, err := OpenSeekable(fs, "file")
+	if err != nil {
+		t.Fatalf("OpenSeekable() error: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+	if _, err := r.Seek(6, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error: %v", err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	if string(b) != "6789" {
+		t.Fatalf("Read after Seek returned %q, want %q", b, "6789")
+	}
+	if _, err := OpenSeekable(fs, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("OpenSeekable() on missing file returned %v, want ErrNotFound", err)
+	}
+}
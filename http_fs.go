@@ -0,0 +1,202 @@
+package simplefs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrReadOnly is returned when writing to a read-only FS.
+var ErrReadOnly = fmt.Errorf("read-only file system")
+
+// httpDirHeader is the response header that marks a response as the JSON
+// listing of a directory, so that it can be told apart from a JSON file.
+const httpDirHeader = "X-Simplefs-Type"
+const httpDirValue = "directory"
+
+// httpDirEntry is an entry in the JSON listing of a directory.
+type httpDirEntry struct {
+	Name    string      `json:"name"`
+	IsDir   bool        `json:"isDir"`
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+}
+
+// HTTPFS returns a read-only FS that reads files from an HTTP server, such as
+// one serving Handler, at baseURL, with names appended to it as URL paths.
+// Open issues a GET and Stat a HEAD request. The server marks directories
+// with the header "X-Simplefs-Type: directory" and lists them as a JSON
+// array of objects with name, isDir, size, mode and modTime fields, which
+// ReadDir requests with "Accept: application/json". A 404 response is
+// returned as an error wrapping ErrNotFound, and Create and Append return an
+// error wrapping ErrReadOnly. If client is nil, http.DefaultClient is used.
+func HTTPFS(baseURL string, client *http.Client) FS {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpFS{baseURL: strings.TrimSuffix(baseURL, "/"), client: client}
+}
+
+type httpFS struct {
+	baseURL string
+	client  *http.Client
+}
+
+// do sends a request for the named path. It returns an *FSError wrapping
+// ErrNotFound for a 404 response, and an error for other unsuccessful ones.
+func (fs *httpFS) do(ctx context.Context, method, op, name string, header http.Header) (*http.Response, error) {
+	p := CleanPath(name)
+	if p == "." {
+		p = ""
+	}
+	u := fs.baseURL + "/" + (&url.URL{Path: p}).EscapedPath()
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := fs.client.Do(req)
+	if err != nil {
+		return nil, &FSError{Op: op, Path: name, Err: err}
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, notFound(op, name)
+	}
+	return nil, fmt.Errorf("cannot %s '%s'. Server returned %s", op, name, resp.Status)
+}
+
+func (fs *httpFS) Open(name string) (File, error) {
+	return fs.OpenContext(context.Background(), name)
+}
+
+// OpenContext implements ContextOpener. The context governs the request,
+// including reading the response body.
+func (fs *httpFS) OpenContext(ctx context.Context, name string) (File, error) {
+	resp, err := fs.do(ctx, http.MethodGet, "open", name, nil)
+	if err != nil {
+		return nil, err
+	}
+	info := httpFileInfo(name, resp)
+	if info.isDir {
+		defer func() { _ = resp.Body.Close() }()
+		entries, err := decodeHTTPDir(name, resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &httpDir{name: name, info: info, readDirEntries: entries}, nil
+	}
+	return &httpFile{ReadCloser: resp.Body, name: name, info: info}, nil
+}
+
+// Stat implements Stater with a HEAD request.
+func (fs *httpFS) Stat(name string) (os.FileInfo, error) {
+	resp, err := fs.do(context.Background(), http.MethodHead, "stat", name, nil)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	return httpFileInfo(name, resp), nil
+}
+
+func (fs *httpFS) ReadDir(name string) ([]DirEntry, error) {
+	resp, err := fs.do(context.Background(), http.MethodGet, "readdir", name, http.Header{"Accept": {"application/json"}})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.Header.Get(httpDirHeader) != httpDirValue {
+		return nil, &FSError{Op: "readdir", Path: name, Err: ErrNotDir}
+	}
+	return decodeHTTPDir(name, resp.Body)
+}
+
+func (fs *httpFS) Create(name string) (io.WriteCloser, error) {
+	return nil, &FSError{Op: "create", Path: name, Err: ErrReadOnly}
+}
+
+func (fs *httpFS) Append(name string) (io.WriteCloser, error) {
+	return nil, &FSError{Op: "append", Path: name, Err: ErrReadOnly}
+}
+
+// httpFileInfo describes the named path from the headers of a response.
+func httpFileInfo(name string, resp *http.Response) *fileInfo {
+	info := &fileInfo{name: path.Base(CleanPath(name)), size: resp.ContentLength, mode: defaultFileMode}
+	if info.size < 0 {
+		info.size = 0
+	}
+	if resp.Header.Get(httpDirHeader) == httpDirValue {
+		info.isDir, info.size, info.mode = true, 0, os.ModeDir|0777
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.modTime = modTime
+	}
+	return info
+}
+
+// decodeHTTPDir decodes the JSON listing of the named directory.
+func decodeHTTPDir(name string, r io.Reader) ([]DirEntry, error) {
+	var listing []httpDirEntry
+	if err := json.NewDecoder(r).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("cannot ReadDir '%s'. Invalid listing: %w", name, err)
+	}
+	entries := make([]DirEntry, len(listing))
+	for i, e := range listing {
+		info := &fileInfo{name: e.Name, size: e.Size, isDir: e.IsDir, mode: e.Mode, modTime: e.ModTime}
+		entries[i] = newDirEntry(info)
+	}
+	sortDirEntries(entries)
+	return entries, nil
+}
+
+// httpFile is a file opened from an HTTPFS. It reads the response body.
+type httpFile struct {
+	io.ReadCloser
+	name string
+	info *fileInfo
+}
+
+func (f *httpFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *httpFile) ReadDir(n int) ([]DirEntry, error) {
+	return nil, &FSError{Op: "readdir", Path: f.name, Err: ErrNotDir}
+}
+
+// httpDir is a directory opened from an HTTPFS. Its listing is read when it
+// is opened.
+type httpDir struct {
+	name           string
+	info           *fileInfo
+	readDirEntries []DirEntry
+}
+
+func (dir *httpDir) Stat() (os.FileInfo, error) {
+	return dir.info, nil
+}
+
+func (dir *httpDir) Read(p []byte) (n int, err error) {
+	return 0, &FSError{Op: "read", Path: dir.name, Err: ErrIsDir}
+}
+
+func (dir *httpDir) Close() error {
+	return nil
+}
+
+func (dir *httpDir) ReadDir(n int) ([]DirEntry, error) {
+	return nextDirEntries(&dir.readDirEntries, n)
+}
@@ -0,0 +1,75 @@
+package simplefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPFS(t *testing.T) {
+	mem := NewMemFSFromStrings(map[string]string{"a.txt": "hello", "dir/b c.txt": "spaced", "dir/sub/d": ""})
+	server := httptest.NewServer(Handler(mem))
+	defer server.Close()
+	fs := HTTPFS(server.URL+"/", nil)
+
+	for name, want := range map[string]string{"a.txt": "hello", "dir/b c.txt": "spaced", "dir/sub/d": ""} {
+		b, err := ReadFile(fs, name)
+		if err != nil || string(b) != want {
+			t.Fatalf("ReadFile(%s) returned %q, %v, want %q", name, b, err, want)
+		}
+	}
+	if _, err := fs.Open("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open() of missing file returned %v, want ErrNotFound", err)
+	}
+
+	for name, want := range map[string]string{".": "[file(a.txt) dir(dir)]", "dir": "[file(b c.txt) dir(sub)]"} {
+		entries, err := fs.ReadDir(name)
+		if err != nil || fmt.Sprint(entries) != want {
+			t.Fatalf("ReadDir(%s) returned %v, %v, want %s", name, entries, err, want)
+		}
+		dir, err := fs.Open(name)
+		if err != nil {
+			t.Fatalf("Open(%s) error: %v", name, err)
+		}
+		if entries, err := dir.ReadDir(-1); err != nil || fmt.Sprint(entries) != want {
+			t.Fatalf("Open(%s).ReadDir() returned %v, %v, want %s", name, entries, err, want)
+		}
+	}
+	if _, err := fs.ReadDir("a.txt"); err == nil {
+		t.Fatalf("ReadDir() on a file returned nil error")
+	}
+	if _, err := fs.ReadDir("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ReadDir() of missing directory returned %v, want ErrNotFound", err)
+	}
+
+	if info, err := Stat(fs, "dir/b c.txt"); err != nil || info.IsDir() || info.Size() != 6 || info.Name() != "b c.txt" {
+		t.Fatalf("Stat() returned %v, %v", info, err)
+	}
+	if info, err := Stat(fs, "dir"); err != nil || !info.IsDir() {
+		t.Fatalf("Stat() on directory returned %v, %v", info, err)
+	}
+
+	if _, err := fs.Create("new"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Create() returned %v, want ErrReadOnly", err)
+	}
+	if _, err := fs.Append("a.txt"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Append() returned %v, want ErrReadOnly", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := OpenContext(ctx, fs, "a.txt"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("OpenContext() with cancelled context returned %v, want context.Canceled", err)
+	}
+	f, err := OpenContext(context.Background(), fs, "a.txt")
+	if err != nil {
+		t.Fatalf("OpenContext() error: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	if b, err := io.ReadAll(f); err != nil || string(b) != "hello" {
+		t.Fatalf("Read() returned %q, %v", b, err)
+	}
+}
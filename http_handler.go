@@ -0,0 +1,159 @@
+package simplefs
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type handler struct {
+	fs     FS
+	writes bool
+}
+
+// HandlerOption configures the handler returned by Handler.
+type HandlerOption func(h *handler)
+
+// WithWrites makes the handler returned by Handler accept PUT requests, which
+// replace the file with the request body using Create, and DELETE requests,
+// which remove it using Remove.
+func WithWrites() HandlerOption {
+	return func(h *handler) {
+		h.writes = true
+	}
+}
+
+// Handler returns an http.Handler that serves fs, with the URL path as the
+// name. GET and HEAD requests for a file serve its contents. If the file is
+// seekable, as the files of MemFS and OsFS are, they are served with
+// http.ServeContent, which supports range and conditional requests; otherwise
+// the contents are copied with the Content-Length taken from Stat and the
+// Content-Type detected by ContentType. Requests for a directory are
+// answered with the JSON listing read by HTTPFS. Paths that do not exist get
+// a 404 response, and paths that escape the root, which can reach the handler
+// through http.StripPrefix, a 400 response. Other methods are rejected unless
+// enabled with WithWrites.
+func Handler(fs FS, opts ...HandlerOption) http.Handler {
+	h := &handler{fs: fs}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := CleanPath(r.URL.Path)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		h.serveGet(w, r, name)
+	case r.Method == http.MethodPut && h.writes:
+		h.servePut(w, r, name)
+	case r.Method == http.MethodDelete && h.writes:
+		if err := Remove(h.fs, name); err != nil {
+			serveError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		allow := "GET, HEAD"
+		if h.writes {
+			allow += ", PUT, DELETE"
+		}
+		w.Header().Set("Allow", allow)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *handler) serveGet(w http.ResponseWriter, r *http.Request, name string) {
+	f, err := h.fs.Open(name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	if isDirFile(f) {
+		entries, err := f.ReadDir(-1)
+		if err != nil {
+			serveError(w, err)
+			return
+		}
+		listing := make([]httpDirEntry, len(entries))
+		for i, entry := range entries {
+			listing[i] = httpDirEntry{Name: entry.Name(), IsDir: entry.IsDir(), Mode: entry.Type()}
+			if info, err := entry.Info(); err == nil {
+				listing[i].Size, listing[i].Mode, listing[i].ModTime = info.Size(), info.Mode(), info.ModTime()
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(httpDirHeader, httpDirValue)
+		if r.Method == http.MethodHead {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(listing)
+		return
+	}
+
+	var modTime time.Time
+	info, err := Stat(h.fs, name)
+	if err == nil {
+		modTime = info.ModTime()
+	}
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, name, modTime, rs)
+		return
+	}
+	if contentType, err := ContentType(h.fs, name); err == nil {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if info != nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	}
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = io.Copy(w, f)
+}
+
+func (h *handler) servePut(w http.ResponseWriter, r *http.Request, name string) {
+	fw, err := h.fs.Create(name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	if _, err := io.Copy(fw, r.Body); err != nil {
+		_ = fw.Close()
+		serveError(w, err)
+		return
+	}
+	if err := fw.Close(); err != nil {
+		serveError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveError responds with the status code that corresponds to err.
+func serveError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrPathEscape):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrNotImplemented), errors.Is(err, ErrReadOnly):
+		http.Error(w, err.Error(), http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
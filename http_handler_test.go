@@ -0,0 +1,111 @@
+package simplefs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	newRequest := func(method, target, body string) *http.Request {
+		return httptest.NewRequest(method, target, strings.NewReader(body))
+	}
+	serve := func(h http.Handler, r *http.Request) *http.Response {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Result()
+	}
+	readBody := func(resp *http.Response) string {
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		t.Run(name, func(t *testing.T) {
+			w, _ := fs.Create("dir/file.txt")
+			_, _ = w.Write([]byte("0123456789"))
+			_ = w.Close()
+			h := Handler(fs)
+
+			resp := serve(h, newRequest(http.MethodGet, "/dir/file.txt", ""))
+			if body := readBody(resp); resp.StatusCode != http.StatusOK || body != "0123456789" {
+				t.Fatalf("GET returned %d %q", resp.StatusCode, body)
+			}
+			if resp.ContentLength != 10 {
+				t.Fatalf("GET returned Content-Length %d, want 10", resp.ContentLength)
+			}
+
+			r := newRequest(http.MethodGet, "/dir/file.txt", "")
+			r.Header.Set("Range", "bytes=2-4")
+			resp = serve(h, r)
+			if body := readBody(resp); resp.StatusCode != http.StatusPartialContent || body != "234" {
+				t.Fatalf("GET with Range returned %d %q, want 206 %q", resp.StatusCode, body, "234")
+			}
+
+			resp = serve(h, newRequest(http.MethodGet, "/dir", ""))
+			if body := readBody(resp); resp.Header.Get(httpDirHeader) != httpDirValue || !strings.Contains(body, `"name":"file.txt"`) {
+				t.Fatalf("GET on directory returned %q", body)
+			}
+
+			if resp := serve(h, newRequest(http.MethodGet, "/missing", "")); resp.StatusCode != http.StatusNotFound {
+				t.Fatalf("GET of missing file returned %d, want 404", resp.StatusCode)
+			}
+			for _, method := range []string{http.MethodPut, http.MethodDelete, http.MethodPost} {
+				if resp := serve(h, newRequest(method, "/dir/file.txt", "new")); resp.StatusCode != http.StatusMethodNotAllowed {
+					t.Fatalf("%s without WithWrites returned %d, want 405", method, resp.StatusCode)
+				}
+			}
+
+			h = Handler(fs, WithWrites())
+			if resp := serve(h, newRequest(http.MethodPut, "/dir/new.txt", "new")); resp.StatusCode != http.StatusNoContent {
+				t.Fatalf("PUT returned %d, want 204", resp.StatusCode)
+			}
+			if b, err := ReadFile(fs, "dir/new.txt"); err != nil || string(b) != "new" {
+				t.Fatalf("File written by PUT contains %q, %v", b, err)
+			}
+			if resp := serve(h, newRequest(http.MethodDelete, "/dir/new.txt", "")); resp.StatusCode != http.StatusNoContent {
+				t.Fatalf("DELETE returned %d, want 204", resp.StatusCode)
+			}
+			if resp := serve(h, newRequest(http.MethodDelete, "/dir/new.txt", "")); resp.StatusCode != http.StatusNotFound {
+				t.Fatalf("DELETE of missing file returned %d, want 404", resp.StatusCode)
+			}
+
+			// http.StripPrefix can pass on a path without a leading slash.
+			stripped := http.StripPrefix("/files", h)
+			for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+				if resp := serve(stripped, newRequest(method, "/files../x", "x")); resp.StatusCode != http.StatusBadRequest {
+					t.Fatalf("%s of escaping path returned %d, want 400", method, resp.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+// unseekableFS hides the Seek method of the files of FS.
+type unseekableFS struct {
+	FS
+}
+
+func (fs unseekableFS) Open(name string) (File, error) {
+	f, err := fs.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ File }{f}, nil
+}
+
+func TestHandlerContentType(t *testing.T) {
+	fs := unseekableFS{NewMemFSFromStrings(map[string]string{
+		"page":     "<html><body>hello</body></html>",
+		"data.pdf": "\x00\x01",
+	})}
+	for name, want := range map[string]string{"page": "text/html; charset=utf-8", "data.pdf": "application/pdf"} {
+		w := httptest.NewRecorder()
+		Handler(fs).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+name, nil))
+		if got := w.Result().Header.Get("Content-Type"); got != want {
+			t.Fatalf("GET %s returned Content-Type %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package simplefs
+
+import (
+	"bytes"
+	"fmt"
+	iofs "io/fs"
+	"strings"
+)
+
+// EqualIOFS reports whether mem and other contain the same set of paths, with
+// the same contents and directory flags. The MemFS tree and other (via
+// fs.WalkDir) are walked in lexical order and compared entry by entry. If
+// they differ, EqualIOFS returns false and an error describing the first
+// mismatch; errors encountered while walking other are returned as is.
+func EqualIOFS(mem *MemFS, other iofs.FS) (bool, error) {
+	mem.init()
+	mem.l.RLock()
+	defer mem.l.RUnlock()
+	var nodes []*dirNode
+	mem.root.DFS(func(node *dirNode) {
+		if node != mem.root {
+			nodes = append(nodes, node)
+		}
+	})
+
+	var i int
+	err := iofs.WalkDir(other, ".", func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			return nil
+		}
+		if i == len(nodes) {
+			return &mismatchError{path: path, msg: "only present in io/fs.FS"}
+		}
+		node := nodes[i]
+		i++
+		if node.Path() != path {
+			if walksBefore(node.Path(), path) {
+				return &mismatchError{path: node.Path(), msg: "only present in MemFS"}
+			}
+			return &mismatchError{path: path, msg: "only present in io/fs.FS"}
+		}
+		if node.IsDirectory() != d.IsDir() {
+			return &mismatchError{path: path, msg: fmt.Sprintf("isDir is %v in MemFS and %v in io/fs.FS", node.IsDirectory(), d.IsDir())}
+		}
+		if d.IsDir() {
+			return nil
+		}
+		b, err := iofs.ReadFile(other, path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(node.B, b) {
+			return &mismatchError{path: path, msg: "contents differ"}
+		}
+		return nil
+	})
+	if err == nil && i < len(nodes) {
+		err = &mismatchError{path: nodes[i].Path(), msg: "only present in MemFS"}
+	}
+	return err == nil, err
+}
+
+// walksBefore reports whether a is visited before b in a depth-first walk
+// that visits the entries of each directory in sorted order. The paths are
+// compared element by element, since comparing them as strings would sort
+// "dir.txt" before "dir/b".
+func walksBefore(a, b string) bool {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return as[i] < bs[i]
+		}
+	}
+	return len(as) < len(bs)
+}
+
+type mismatchError struct {
+	path string
+	msg  string
+}
+
+func (err *mismatchError) Error() string {
+	return fmt.Sprintf("mismatch at '%s': %s", err.path, err.msg)
+}
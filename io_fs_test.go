@@ -0,0 +1,73 @@
+package simplefs
+
+import (
+	iofs "io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEqualIOFS(t *testing.T) {
+	mem := &MemFS{}
+	mem.SetString("a", "a")
+	mem.SetString("dir/b", "b")
+	mem.SetString("dir/sub/c", "c")
+
+	other := fstest.MapFS{
+		"a":         {Data: []byte("a")},
+		"dir/b":     {Data: []byte("b")},
+		"dir/sub/c": {Data: []byte("c")},
+	}
+	if ok, err := EqualIOFS(mem, other); !ok || err != nil {
+		t.Fatalf("EqualIOFS() returned %v, %v", ok, err)
+	}
+
+	tests := map[string]fstest.MapFS{
+		"different contents": {
+			"a": {Data: []byte("a")}, "dir/b": {Data: []byte("B")}, "dir/sub/c": {Data: []byte("c")},
+		},
+		"missing file": {
+			"a": {Data: []byte("a")}, "dir/sub/c": {Data: []byte("c")},
+		},
+		"extra file": {
+			"a": {Data: []byte("a")}, "dir/b": {Data: []byte("b")}, "dir/sub/c": {Data: []byte("c")}, "z": {},
+		},
+		"file instead of dir": {
+			"a": {Data: []byte("a")}, "dir/b": {Data: []byte("b")}, "dir/sub": {Data: []byte("c")},
+		},
+	}
+	for name, other := range tests {
+		if ok, err := EqualIOFS(mem, other); ok || err == nil {
+			t.Fatalf("%s: EqualIOFS() returned %v, %v", name, ok, err)
+		}
+	}
+}
+
+func TestEqualIOFSMismatchSide(t *testing.T) {
+	mem := NewMemFSFromStrings(map[string]string{"dir/b": "b", "dir.txt": "x"})
+	tests := map[string]struct {
+		other fstest.MapFS
+		want  string
+	}{
+		"only in MemFS": {
+			fstest.MapFS{"dir": {Mode: iofs.ModeDir}, "dir.txt": {Data: []byte("x")}},
+			"mismatch at 'dir/b': only present in MemFS",
+		},
+		"only in io/fs.FS": {
+			fstest.MapFS{"dir/a": {}, "dir/b": {Data: []byte("b")}, "dir.txt": {Data: []byte("x")}},
+			"mismatch at 'dir/a': only present in io/fs.FS",
+		},
+		"only in MemFS at end": {
+			fstest.MapFS{"dir/b": {Data: []byte("b")}},
+			"mismatch at 'dir.txt': only present in MemFS",
+		},
+		"only in io/fs.FS at end": {
+			fstest.MapFS{"dir/b": {Data: []byte("b")}, "dir.txt": {Data: []byte("x")}, "z": {}},
+			"mismatch at 'z': only present in io/fs.FS",
+		},
+	}
+	for name, test := range tests {
+		if _, err := EqualIOFS(mem, test.other); err == nil || err.Error() != test.want {
+			t.Fatalf("%s: EqualIOFS() returned %v, want %q", name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package simplefs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ReadJSONLines streams the named newline-delimited JSON file, calling fn
+// with each line. Blank lines are skipped. A line that is not valid JSON
+// stops the read with an error reporting its line number, as does an error
+// returned by fn.
+func ReadJSONLines(fs FS, name string, fn func(raw json.RawMessage) error) error {
+	f, err := fs.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	r := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var raw json.RawMessage
+			if jsonErr := json.Unmarshal(line, &raw); jsonErr != nil {
+				return fmt.Errorf("%s:%d: %w", name, lineNo, jsonErr)
+			}
+			if fnErr := fn(raw); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// ScanLines opens the named file and returns a bufio.Scanner over its lines,
+// and a function that closes the file. The caller must call the close
+// function when done scanning.
+func ScanLines(fs FS, name string) (*bufio.Scanner, func() error, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bufio.NewScanner(f), f.Close, nil
+}
+
+// ForEachLine calls fn with each line of the named file, without the line
+// ending, and closes the file when done. The line is only valid until fn
+// returns. An error returned by fn stops the read and is returned. Lines
+// longer than bufio.MaxScanTokenSize are reported as bufio.ErrTooLong.
+func ForEachLine(fs FS, name string, fn func(line []byte) error) error {
+	scanner, closeFn, err := ScanLines(fs, name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeFn() }()
+	for scanner.Scan() {
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
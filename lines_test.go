@@ -0,0 +1,62 @@
+package simplefs
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadJSONLines(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{
+		"ok.jsonl":     "{\"a\":1}\n\n[2, 3]\n\"four\"",
+		"broken.jsonl": "{\"a\":1}\n{\"b\":\n3\n",
+	})
+
+	var got []string
+	err := ReadJSONLines(fs, "ok.jsonl", func(raw json.RawMessage) error {
+		got = append(got, string(raw))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadJSONLines() error: %v", err)
+	}
+	if strings.Join(got, "|") != "{\"a\":1}|[2, 3]|\"four\"" {
+		t.Fatalf("ReadJSONLines() read %v", got)
+	}
+
+	err = ReadJSONLines(fs, "broken.jsonl", func(raw json.RawMessage) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "broken.jsonl:2:") {
+		t.Fatalf("ReadJSONLines() on malformed line returned %v", err)
+	}
+}
+
+func TestForEachLine(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{"log": "one\r\ntwo\n\nthree"})
+
+	var got []string
+	err := ForEachLine(fs, "log", func(line []byte) error {
+		got = append(got, string(line))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachLine() error: %v", err)
+	}
+	if strings.Join(got, "|") != "one|two||three" {
+		t.Fatalf("ForEachLine() read %q", got)
+	}
+
+	stop := errors.New("stop")
+	var n int
+	err = ForEachLine(fs, "log", func(line []byte) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Fatalf("ForEachLine() returned %v after %d lines, want %v after 1", err, n, stop)
+	}
+
+	if err := ForEachLine(fs, "missing", nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ForEachLine(missing) returned %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,122 @@
+package simplefs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ErrCorruptRecord is returned by LogReader.Next for a record whose checksum
+// does not match its contents.
+var ErrCorruptRecord = fmt.Errorf("corrupt log record")
+
+// logHeaderSize is the size of the header framing each log record: the
+// length of the record and the CRC-32 (IEEE) of its contents, both as
+// big-endian uint32.
+const logHeaderSize = 8
+
+// LogWriter appends length-prefixed, checksummed records to a file.
+type LogWriter struct {
+	fs   FS
+	name string
+}
+
+// OpenLog returns a LogWriter that appends records to the named file,
+// creating it if it does not exist.
+func OpenLog(fs FS, name string) (*LogWriter, error) {
+	w, err := fs.Append(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return &LogWriter{fs: fs, name: name}, nil
+}
+
+// Append appends rec to the log as a single record. The header and contents
+// are written with a single Write to a writer from fs.Append, so each
+// record is committed as a whole when that writer is closed.
+func (l *LogWriter) Append(rec []byte) error {
+	if uint64(len(rec)) > 1<<32-1 {
+		return fmt.Errorf("cannot append to log '%s'. Record of %d bytes is too large", l.name, len(rec))
+	}
+	frame := make([]byte, logHeaderSize+len(rec))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(rec)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(rec))
+	copy(frame[logHeaderSize:], rec)
+
+	w, err := l.fs.Append(l.name)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(frame); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// LogReader reads the records written by a LogWriter.
+type LogReader struct {
+	name string
+	f    File
+	r    *bufio.Reader
+	off  int64
+	err  error
+}
+
+// OpenLogReader opens the named log for reading its records from the start.
+func OpenLogReader(fs FS, name string) (*LogReader, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &LogReader{name: name, f: f, r: bufio.NewReader(f)}, nil
+}
+
+// Next returns the next record, or io.EOF after the last one. A trailing
+// record that is incomplete, as left by a write that was interrupted, is
+// treated as the end of the log. A record whose checksum does not match
+// returns an error wrapping ErrCorruptRecord, and so does every following
+// call, since the framing after it cannot be trusted.
+func (l *LogReader) Next() ([]byte, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	var header [logHeaderSize]byte
+	if _, err := io.ReadFull(l.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, err
+	}
+	n := int64(binary.BigEndian.Uint32(header[0:4]))
+	// Copy rather than allocate n bytes up front, so that a corrupt length
+	// cannot cause a huge allocation.
+	var buf bytes.Buffer
+	if copied, err := io.CopyN(&buf, l.r, n); err != nil {
+		if err == io.EOF && copied < n {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	rec := buf.Bytes()
+	if rec == nil {
+		rec = []byte{}
+	}
+	if crc32.ChecksumIEEE(rec) != binary.BigEndian.Uint32(header[4:8]) {
+		l.err = fmt.Errorf("%s: record at offset %d: %w", l.name, l.off, ErrCorruptRecord)
+		return nil, l.err
+	}
+	l.off += logHeaderSize + n
+	return rec, nil
+}
+
+// Close closes the underlying file.
+func (l *LogReader) Close() error {
+	return l.f.Close()
+}
@@ -0,0 +1,84 @@
+package simplefs
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	readAll := func(fs FS) ([]string, error) {
+		r, err := OpenLogReader(fs, "log")
+		if err != nil {
+			t.Fatalf("OpenLogReader() error: %v", err)
+		}
+		defer func() { _ = r.Close() }()
+		var records []string
+		for {
+			rec, err := r.Next()
+			if err == io.EOF {
+				return records, nil
+			}
+			if err != nil {
+				return records, err
+			}
+			records = append(records, string(rec))
+		}
+	}
+
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		l, err := OpenLog(fs, "log")
+		if err != nil {
+			t.Fatalf("%s: OpenLog() error: %v", name, err)
+		}
+		if records, err := readAll(fs); err != nil || len(records) != 0 {
+			t.Fatalf("%s: empty log read %q, %v", name, records, err)
+		}
+		for _, rec := range []string{"one", "", "three"} {
+			if err := l.Append([]byte(rec)); err != nil {
+				t.Fatalf("%s: Append() error: %v", name, err)
+			}
+		}
+		records, err := readAll(fs)
+		if err != nil {
+			t.Fatalf("%s: reading log error: %v", name, err)
+		}
+		if strings.Join(records, "|") != "one||three" {
+			t.Fatalf("%s: log read %q", name, records)
+		}
+	}
+}
+
+func TestLogTruncatedAndCorrupt(t *testing.T) {
+	fs := &MemFS{}
+	l, _ := OpenLog(fs, "log")
+	_ = l.Append([]byte("first"))
+	_ = l.Append([]byte("second"))
+	full, _ := fs.Bytes("log")
+
+	next := func() ([]byte, error) {
+		r, err := OpenLogReader(fs, "log")
+		if err != nil {
+			t.Fatalf("OpenLogReader() error: %v", err)
+		}
+		if rec, err := r.Next(); err != nil || string(rec) != "first" {
+			t.Fatalf("Next() returned %q, %v, want %q", rec, err, "first")
+		}
+		return r.Next()
+	}
+
+	for _, n := range []int{len(full) - 1, len(full) - len("second"), len(full) - len("second") - 3} {
+		fs.SetBytes("log", full[:n])
+		if rec, err := next(); err != io.EOF {
+			t.Fatalf("Next() on log truncated to %d bytes returned %q, %v, want EOF", n, rec, err)
+		}
+	}
+
+	corrupt := append([]byte{}, full...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	fs.SetBytes("log", corrupt)
+	if _, err := next(); !errors.Is(err, ErrCorruptRecord) {
+		t.Fatalf("Next() on corrupt record returned %v, want ErrCorruptRecord", err)
+	}
+}
@@ -0,0 +1,150 @@
+package simplefs
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Logged returns an FS that reports every Open, ReadDir, Stat, Create and
+// Append call on fs to logf, with the name and the resulting error. Writers
+// returned by Create and Append also report the number of bytes written when
+// they are closed. Lstat, MkdirAll, Remove, RemoveAll, Rename, OpenFile,
+// Symlink and Readlink are forwarded to fs and logged the same way, and
+// return an error wrapping ErrNotImplemented if fs does not support them.
+// The calls themselves are passed through unchanged. Other optional
+// interfaces of fs, such as ModeCreator, are not available through Logged.
+func Logged(fs FS, logf func(format string, args ...interface{})) FS {
+	return &loggedFS{fs: fs, logf: logf}
+}
+
+type loggedFS struct {
+	fs   FS
+	logf func(format string, args ...interface{})
+}
+
+func (fs *loggedFS) Unwrap() FS {
+	return fs.fs
+}
+
+func (fs *loggedFS) Open(name string) (File, error) {
+	f, err := fs.fs.Open(name)
+	fs.logf("open %s: %v", name, err)
+	return f, err
+}
+
+func (fs *loggedFS) ReadDir(name string) ([]DirEntry, error) {
+	entries, err := fs.fs.ReadDir(name)
+	fs.logf("readdir %s: %d entries, %v", name, len(entries), err)
+	return entries, err
+}
+
+func (fs *loggedFS) Stat(name string) (os.FileInfo, error) {
+	info, err := Stat(fs.fs, name)
+	fs.logf("stat %s: %v", name, err)
+	return info, err
+}
+
+func (fs *loggedFS) Lstat(name string) (os.FileInfo, error) {
+	info, err := Lstat(fs.fs, name)
+	fs.logf("lstat %s: %v", name, err)
+	return info, err
+}
+
+func (fs *loggedFS) MkdirAll(name string) error {
+	err := MkdirAll(fs.fs, name)
+	fs.logf("mkdirall %s: %v", name, err)
+	return err
+}
+
+func (fs *loggedFS) Remove(name string) error {
+	err := Remove(fs.fs, name)
+	fs.logf("remove %s: %v", name, err)
+	return err
+}
+
+func (fs *loggedFS) RemoveAll(name string) error {
+	err := RemoveAll(fs.fs, name)
+	fs.logf("removeall %s: %v", name, err)
+	return err
+}
+
+func (fs *loggedFS) Rename(oldName, newName string) error {
+	err := Rename(fs.fs, oldName, newName)
+	fs.logf("rename %s %s: %v", oldName, newName, err)
+	return err
+}
+
+func (fs *loggedFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := OpenFile(fs.fs, name, flag, perm)
+	fs.logf("openfile %s: %v", name, err)
+	return f, err
+}
+
+func (fs *loggedFS) Symlink(target, linkName string) error {
+	var err error
+	if s, ok := fs.fs.(Symlinker); ok {
+		err = s.Symlink(target, linkName)
+	} else {
+		err = fmt.Errorf("cannot create symbolic link '%s'. %T does not implement Symlinker: %w", linkName, fs.fs, ErrNotImplemented)
+	}
+	fs.logf("symlink %s %s: %v", target, linkName, err)
+	return err
+}
+
+func (fs *loggedFS) Readlink(name string) (string, error) {
+	var target string
+	var err error
+	if s, ok := fs.fs.(Symlinker); ok {
+		target, err = s.Readlink(name)
+	} else {
+		err = fmt.Errorf("cannot read symbolic link '%s'. %T does not implement Symlinker: %w", name, fs.fs, ErrNotImplemented)
+	}
+	fs.logf("readlink %s: %v", name, err)
+	return target, err
+}
+
+func (fs *loggedFS) Create(name string) (io.WriteCloser, error) {
+	w, err := fs.fs.Create(name)
+	fs.logf("create %s: %v", name, err)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedWriter{fs: fs, op: "create", name: name, w: w}, nil
+}
+
+func (fs *loggedFS) Append(name string) (io.WriteCloser, error) {
+	w, err := fs.fs.Append(name)
+	fs.logf("append %s: %v", name, err)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedWriter{fs: fs, op: "append", name: name, w: w}, nil
+}
+
+// loggedWriter counts the bytes written so that they can be logged on Close.
+type loggedWriter struct {
+	fs      *loggedFS
+	op      string
+	name    string
+	w       io.WriteCloser
+	written int64
+}
+
+func (w *loggedWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Sync implements Syncer by syncing the underlying writer, so that wrapping
+// does not hide its Sync method.
+func (w *loggedWriter) Sync() error {
+	return Sync(w.w)
+}
+
+func (w *loggedWriter) Close() error {
+	err := w.w.Close()
+	w.fs.logf("close %s %s: %d bytes written, %v", w.op, w.name, w.written, err)
+	return err
+}
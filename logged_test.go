@@ -0,0 +1,101 @@
+package simplefs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLogged(t *testing.T) {
+	mem := NewMemFSFromStrings(map[string]string{"existing": "abc"})
+	var lines []string
+	fs := Logged(mem, func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+
+	if _, err := fs.Open("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open() returned %v, want ErrNotFound", err)
+	}
+	w, _ := fs.Create("dir/new")
+	_, _ = w.Write([]byte("hello"))
+	_ = w.Close()
+	w, _ = fs.Append("existing")
+	_, _ = w.Write([]byte("de"))
+	_ = w.Close()
+	if _, err := fs.ReadDir("."); err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+
+	want := []string{
+		"open missing: open missing: not found",
+		"create dir/new: <nil>",
+		"close create dir/new: 5 bytes written, <nil>",
+		"append existing: <nil>",
+		"close append existing: 2 bytes written, <nil>",
+		"readdir .: 2 entries, <nil>",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("Logged %q, want %q", lines, want)
+	}
+	if b, _ := mem.Bytes("existing"); string(b) != "abcde" {
+		t.Fatalf("Underlying file contains %q, want %q", b, "abcde")
+	}
+	if Unwrap(fs) != mem {
+		t.Fatalf("Unwrap() did not return the wrapped FS")
+	}
+}
+
+func TestLoggedForwardsOptionalInterfaces(t *testing.T) {
+	mem := NewMemFSFromStrings(map[string]string{"file": "abc"})
+	var lines []string
+	fs := Logged(mem, func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+
+	if err := MkdirAll(fs, "dir"); err != nil {
+		t.Fatalf("MkdirAll() error: %v", err)
+	}
+	if err := Rename(fs, "file", "dir/file"); err != nil {
+		t.Fatalf("Rename() error: %v", err)
+	}
+	if err := fs.(Symlinker).Symlink("dir/file", "link"); err != nil {
+		t.Fatalf("Symlink() error: %v", err)
+	}
+	if target, err := fs.(Symlinker).Readlink("link"); err != nil || target != "dir/file" {
+		t.Fatalf("Readlink() returned %q, %v, want %q", target, err, "dir/file")
+	}
+	f, err := OpenFile(fs, "dir/file", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error: %v", err)
+	}
+	_ = f.Close()
+	if err := Remove(fs, "link"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	if err := RemoveAll(fs, "dir"); err != nil {
+		t.Fatalf("RemoveAll() error: %v", err)
+	}
+
+	want := []string{
+		"mkdirall dir: <nil>",
+		"rename file dir/file: <nil>",
+		"symlink dir/file link: <nil>",
+		"readlink link: <nil>",
+		"openfile dir/file: <nil>",
+		"remove link: <nil>",
+		"removeall dir: <nil>",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("Logged %q, want %q", lines, want)
+	}
+	if entries, err := mem.ReadDir("."); err != nil || len(entries) != 0 {
+		t.Fatalf("Underlying ReadDir() returned %v, %v, want no entries", entries, err)
+	}
+
+	// Without support in the wrapped FS, the forwarded calls are not implemented.
+	if err := Remove(Logged(unseekableFS{mem}, func(string, ...interface{}) {}), "x"); !errors.Is(err, ErrNotImplemented) {
+		t.Fatalf("Remove() returned %v, want ErrNotImplemented", err)
+	}
+}
@@ -4,18 +4,83 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
+// ErrTooManyFiles is returned when creating a file would exceed the limit
+// set with WithMaxFiles.
+var ErrTooManyFiles = fmt.Errorf("too many files")
+
 type MemFS struct {
-	root *dirNode
-	l    sync.RWMutex
+	root     *dirNode
+	l        sync.RWMutex
+	watchers watchers
+
+	// numFiles is the number of files and symbolic links in the tree, and
+	// maxFiles the limit set with WithMaxFiles, or 0 for no limit.
+	numFiles int
+	maxFiles int
+
+	// removed holds the paths of the files and symbolic links removed since
+	// the last call to RemovedFiles.
+	removed map[string]bool
+}
+
+// MemOption configures the MemFS returned by NewMemFS.
+type MemOption func(fs *MemFS)
+
+// WithMaxFiles limits the number of files in the MemFS to n. Creating a file
+// beyond the limit fails with an error wrapping ErrTooManyFiles, while files
+// that already exist can still be written to. Symbolic links count as files,
+// and removing a file frees its slot. Files passed to NewMemFS count towards
+// the limit but are always added.
+func WithMaxFiles(n int) MemOption {
+	return func(fs *MemFS) {
+		fs.maxFiles = n
+	}
+}
+
+// NewMemFS returns a MemFS containing the given files, keyed by path.
+// Parent directories are created as needed, and the contents are copied.
+// Names that escape the root, such as "../a", are ignored.
+func NewMemFS(files map[string][]byte, opts ...MemOption) *MemFS {
+	fs := &MemFS{root: &dirNode{}}
+	now := time.Now()
+	for name, b := range files {
+		path, err := memPath("create", name)
+		if err != nil {
+			continue
+		}
+		b = append(make([]byte, 0, len(b)), b...)
+		node := fs.root.GetOrAdd(b, path...)
+		node.B = b
+		node.ModTime = now
+	}
+	fs.numFiles = countFiles(fs.root)
+	for _, opt := range opts {
+		opt(fs)
+	}
+	return fs
+}
+
+// NewMemFSFromStrings is like NewMemFS but takes the contents as strings.
+func NewMemFSFromStrings(files map[string]string, opts ...MemOption) *MemFS {
+	b := make(map[string][]byte, len(files))
+	for name, s := range files {
+		b[name] = []byte(s)
+	}
+	return NewMemFS(b, opts...)
 }
 
 func (fs *MemFS) SetBytes(name string, b []byte) {
-	w, _ := fs.Create(name)
+	w, err := fs.Create(name)
+	if err != nil {
+		return
+	}
 	_, _ = w.Write(b)
 	_ = w.Close()
 }
@@ -24,6 +89,29 @@ func (fs *MemFS) SetString(name string, s string) {
 	fs.SetBytes(name, []byte(s))
 }
 
+// Replace sets the contents of the named file to a copy of b, creating the
+// file if it does not exist. The contents are swapped in a single locked
+// operation, so readers see either the complete old or the complete new
+// contents, which makes it suitable for hot-reloading configuration.
+func (fs *MemFS) Replace(name string, b []byte) error {
+	b = append(make([]byte, 0, len(b)), b...)
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	path, err := memPath("replace", name)
+	if err != nil {
+		return err
+	}
+	node, err := fs.root.Lookup(true, path...)
+	if err != nil {
+		return err
+	}
+	if node != nil && node.IsDirectory() {
+		return &FSError{Op: "replace", Path: name, Err: ErrIsDir}
+	}
+	return fs.setBytes(name, b, keepMode)
+}
+
 func (fs *MemFS) init() {
 	fs.l.Lock()
 	if fs.root == nil {
@@ -33,69 +121,805 @@ func (fs *MemFS) init() {
 }
 
 func (fs *MemFS) Create(name string) (io.WriteCloser, error) {
+	return fs.create(name, keepMode)
+}
+
+// CreateMode is like Create but sets the file's mode to the given mode.
+func (fs *MemFS) CreateMode(name string, mode os.FileMode) (io.WriteCloser, error) {
+	return fs.create(name, mode.Perm())
+}
+
+// keepMode is passed to create and setBytes instead of a mode to keep the
+// mode of an existing file, or use the default mode for a new file. It is not
+// a valid permission, so any mode given to CreateMode, including 0, is set.
+const keepMode = ^os.FileMode(0)
+
+// create creates the named file with the given mode, or keepMode. It returns
+// an error wrapping ErrIsDir if name is a directory.
+func (fs *MemFS) create(name string, mode os.FileMode) (io.WriteCloser, error) {
 	fs.init()
 	fs.l.Lock()
 	defer fs.l.Unlock()
-	var buf bytes.Buffer
-	addNode := func() error {
+	path, err := memPath("create", name)
+	if err != nil {
+		return nil, err
+	}
+	if node := fs.root.Get(path...); node != nil && node.IsDirectory() {
+		return nil, &FSError{Op: "create", Path: name, Err: ErrIsDir}
+	}
+	if err := fs.checkFileLimit(path...); err != nil {
+		return nil, &FSError{Op: "create", Path: name, Err: err}
+	}
+	return newMemWriter(func(b []byte) error {
 		fs.l.Lock()
 		defer fs.l.Unlock()
-		b := getBytes(&buf)
-		node := fs.root.GetOrAdd(b, nameToPath(name)...)
-		node.B = b
+		return fs.setBytes(name, cloneBytes(b), mode)
+	}), nil
+}
+
+// CreateExcl implements ExclCreator. The name is reserved as an empty file
+// under the write lock before CreateExcl returns, so concurrent calls for the
+// same path cannot both succeed. The contents are committed on Close.
+func (fs *MemFS) CreateExcl(name string) (io.WriteCloser, error) {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	path, err := memPath("create", name)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := fs.root.Lookup(false, path...)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, &FSError{Op: "create", Path: name, Err: ErrExist}
+	}
+	node, err := fs.addFile(make([]byte, 0), path...)
+	if err != nil {
+		return nil, &FSError{Op: "create", Path: name, Err: err}
+	}
+	if node == nil {
+		return nil, fmt.Errorf("cannot create '%s'. Path is a dangling symbolic link", name)
+	}
+	node.Dirty = true
+	return newMemWriter(func(b []byte) error {
+		fs.l.Lock()
+		defer fs.l.Unlock()
+		return fs.setBytes(name, cloneBytes(b), keepMode)
+	}), nil
+}
+
+// setBytes replaces the contents of the named file with b, creating it if
+// needed, and sets its mode unless mode is keepMode. A directory is left
+// intact, and an error wrapping ErrIsDir returned. The caller must hold the
+// write lock.
+func (fs *MemFS) setBytes(name string, b []byte, mode os.FileMode) error {
+	path, err := memPath("create", name)
+	if err != nil {
+		return err
+	}
+	node, err := fs.addFile(b, path...)
+	if err != nil {
+		return &FSError{Op: "create", Path: name, Err: err}
+	}
+	if node == nil {
+		return fmt.Errorf("cannot create '%s'. Path is a dangling symbolic link", name)
+	}
+	if node.IsDirectory() {
+		return &FSError{Op: "create", Path: name, Err: ErrIsDir}
+	}
+	node.B = b
+	node.Dirty = true
+	node.ModTime = time.Now()
+	if mode != keepMode {
+		node.Mode = mode
+		node.ModeSet = true
+	}
+	fs.watchers.emit(node.Path(), OpCreate)
+	return nil
+}
+
+// CreateSparse creates the named file filled with size zero bytes. MemFS has
+// no sparse representation, so the zeros are allocated. A negative size is an
+// error, and leaves an existing file unchanged.
+func (fs *MemFS) CreateSparse(name string, size int64) error {
+	if size < 0 {
+		return fmt.Errorf("cannot create '%s'. Size %d is negative", name, size)
+	}
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	return fs.setBytes(name, make([]byte, size), keepMode)
+}
+
+// checkFileLimit returns ErrTooManyFiles if there is no node at path and
+// adding one would exceed the limit set with WithMaxFiles. The caller must
+// hold the lock.
+func (fs *MemFS) checkFileLimit(path ...string) error {
+	if fs.maxFiles <= 0 || fs.numFiles < fs.maxFiles {
+		return nil
+	}
+	if fs.root.Get(path...) != nil {
 		return nil
 	}
-	return &writeCloser{w: &buf, closeFn: addNode}, nil
+	return ErrTooManyFiles
 }
 
-func (fs *MemFS) Append(name string) (io.WriteCloser, error) {
+// addFile returns the node at path, adding a file with contents b if there
+// is none. Like AddDescendant, it returns nil if the path cannot be created.
+// The caller must hold the write lock.
+func (fs *MemFS) addFile(b []byte, path ...string) (*dirNode, error) {
+	if node := fs.root.Get(path...); node != nil {
+		return node, nil
+	}
+	if err := fs.checkFileLimit(path...); err != nil {
+		return nil, err
+	}
+	node := fs.root.AddDescendant(b, path...)
+	if node != nil {
+		fs.numFiles++
+	}
+	return node, nil
+}
+
+// countFiles returns the number of files and symbolic links in the tree
+// rooted at node.
+func countFiles(node *dirNode) int {
+	var n int
+	node.DFS(func(node *dirNode) {
+		if !node.IsDirectory() {
+			n++
+		}
+	})
+	return n
+}
+
+// Touch creates the named file empty if it does not exist, and otherwise sets
+// its modification time to now. Touching a file does not mark it dirty.
+func (fs *MemFS) Touch(name string) error {
 	fs.init()
 	fs.l.Lock()
-	got := fs.root.Get(nameToPath(name)...)
-	fs.l.Unlock()
-	if got == nil {
-		return fs.Create(name)
+	defer fs.l.Unlock()
+	path, err := memPath("touch", name)
+	if err != nil {
+		return err
+	}
+	node, err := fs.root.Lookup(true, path...)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return fs.setBytes(name, []byte{}, keepMode)
+	}
+	node.ModTime = time.Now()
+	return nil
+}
+
+// Append returns a writer that appends to the named file on Close, creating
+// the file if it does not exist by then. The file is looked up when the
+// writer is closed, so that concurrent writers never append to a node that
+// has since been replaced or removed. Appending to a directory fails with an
+// error wrapping ErrIsDir.
+func (fs *MemFS) Append(name string) (io.WriteCloser, error) {
+	path, err := memPath("append", name)
+	if err != nil {
+		return nil, err
+	}
+	fs.init()
+	fs.l.RLock()
+	node, err := fs.root.Lookup(true, path...)
+	if err == nil && node != nil && node.IsDirectory() {
+		err = ErrIsDir
+	} else if err == nil {
+		err = fs.checkFileLimit(path...)
 	}
-	var buf bytes.Buffer
-	updateNode := func() error {
+	fs.l.RUnlock()
+	if err != nil {
+		return nil, &FSError{Op: "append", Path: name, Err: err}
+	}
+	return newMemWriter(func(b []byte) error {
 		fs.l.Lock()
 		defer fs.l.Unlock()
-		b := getBytes(&buf)
+		got, err := fs.root.Lookup(true, path...)
+		if err != nil {
+			return err
+		}
+		if got == nil {
+			return fs.setBytes(name, cloneBytes(b), keepMode)
+		}
+		if got.IsDirectory() {
+			return &FSError{Op: "append", Path: name, Err: ErrIsDir}
+		}
+		// Appending writes past len(got.B) only, so readers that hold the
+		// current slice keep their snapshot even if the array is reused.
 		got.B = append(got.B, b...)
+		got.Dirty = true
+		got.ModTime = time.Now()
+		fs.watchers.emit(got.Path(), OpAppend)
 		return nil
-	}
-	return &writeCloser{w: &buf, closeFn: updateNode}, nil
+	}), nil
 }
 
+// Open opens the named file or directory. A file is opened as a snapshot:
+// the reader sees the contents as they were when it was opened, regardless
+// of later writes. Snapshots share the file's bytes rather than copying
+// them, so any number of readers of a large file cost no extra memory.
 func (fs *MemFS) Open(name string) (File, error) {
 	fs.init()
 	fs.l.RLock()
 	defer fs.l.RUnlock()
-	node := fs.root.Get(nameToPath(name)...)
+	path, err := memPath("open", name)
+	if err != nil {
+		return nil, err
+	}
+	node, err := fs.root.Lookup(true, path...)
+	if err != nil {
+		return nil, &FSError{Op: "open", Path: name, Err: err}
+	}
+	if node == nil {
+		return nil, notFound("open", name)
+	}
+	if node.IsDirectory() {
+		return &memDir{fs: fs, name: name, info: node.FileInfo()}, nil
+	} else {
+		// node.B is never modified in place: writers replace it, and
+		// Append only writes past the end of the slice held by readers.
+		return &memFile{Reader: bytes.NewReader(node.B), name: name, info: node.FileInfo()}, nil
+	}
+}
+
+// Bytes returns a copy of the contents of the named file. It is equivalent
+// to reading the file with Open, but avoids the reader.
+func (fs *MemFS) Bytes(name string) ([]byte, error) {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+	path, err := memPath("read", name)
+	if err != nil {
+		return nil, err
+	}
+	node, err := fs.root.Lookup(true, path...)
+	if err != nil {
+		return nil, err
+	}
 	if node == nil {
-		return nil, ErrNotFound
+		return nil, notFound("read", name)
 	}
 	if node.IsDirectory() {
-		return &memDir{fs: fs, name: name}, nil
+		return nil, &FSError{Op: "read", Path: name, Err: ErrIsDir}
+	}
+	return append(make([]byte, 0, len(node.B)), node.B...), nil
+}
+
+func (fs *MemFS) Stat(name string) (os.FileInfo, error) {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+	path, err := memPath("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	node, err := fs.root.Lookup(true, path...)
+	if err != nil {
+		return nil, &FSError{Op: "stat", Path: name, Err: err}
+	}
+	if node == nil {
+		return nil, notFound("stat", name)
+	}
+	return node.FileInfo(), nil
+}
+
+// Lstat implements Lstater. A symbolic link is described by its own node,
+// with os.ModeSymlink set and the length of the target as its size.
+func (fs *MemFS) Lstat(name string) (os.FileInfo, error) {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+	path, err := memPath("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	node, err := fs.root.Lookup(false, path...)
+	if err != nil {
+		return nil, &FSError{Op: "lstat", Path: name, Err: err}
+	}
+	if node == nil {
+		return nil, notFound("lstat", name)
+	}
+	return node.FileInfo(), nil
+}
+
+// Symlink creates linkName as a symbolic link to target. Relative targets are
+// resolved from the directory containing the link, and absolute targets from
+// the root of the MemFS.
+func (fs *MemFS) Symlink(target, linkName string) error {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	path, err := memPath("symlink", linkName)
+	if err != nil {
+		return err
+	}
+	existing, err := fs.root.Lookup(false, path...)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("cannot create symbolic link '%s'. Path exists", linkName)
+	}
+	node, err := fs.addFile(nil, path...)
+	if err != nil {
+		return &FSError{Op: "symlink", Path: linkName, Err: err}
+	}
+	if node == nil {
+		return fmt.Errorf("cannot create symbolic link '%s'. Parent is a dangling symbolic link", linkName)
+	}
+	node.LinkTarget = target
+	return nil
+}
+
+// MkdirAll implements DirMaker. Symbolic links to directories along the path
+// are followed.
+func (fs *MemFS) MkdirAll(name string) error {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	path, err := memPath("mkdir", name)
+	if err != nil {
+		return err
+	}
+	for i := 1; i <= len(path); i++ {
+		if node := fs.root.Get(path[:i]...); node != nil && !node.IsDirectory() {
+			return fmt.Errorf("cannot create directory '%s'. '%s' is a file", name, strings.Join(path[:i], "/"))
+		}
+	}
+	if fs.root.GetOrAdd(nil, path...) == nil {
+		return fmt.Errorf("cannot create directory '%s'. Path is a dangling symbolic link", name)
+	}
+	return nil
+}
+
+// Remove removes the named file or empty directory. Symbolic links are
+// removed themselves, not their targets.
+func (fs *MemFS) Remove(name string) error {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	path, err := memPath("remove", name)
+	if err != nil {
+		return err
+	}
+	node, err := fs.root.Lookup(false, path...)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return notFound("remove", name)
+	}
+	if node.IsDirectory() && len(node.Children) > 0 {
+		return fmt.Errorf("cannot remove '%s'. Directory is not empty", name)
+	}
+	nodePath := node.Path()
+	fs.recordRemoved(node)
+	if err := node.Unlink(); err != nil {
+		return err
+	}
+	fs.numFiles -= countFiles(node)
+	fs.watchers.emit(nodePath, OpRemove)
+	return nil
+}
+
+// RemoveAll removes the named file or directory and everything it contains.
+// It returns nil if the path does not exist.
+func (fs *MemFS) RemoveAll(name string) error {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	path, err := memPath("remove", name)
+	if err != nil {
+		return err
+	}
+	node, err := fs.root.Lookup(false, path...)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return nil
+	}
+	if node == fs.root {
+		fs.recordRemoved(node)
+		for _, child := range node.Children {
+			fs.watchers.emit(child.Path(), OpRemove)
+		}
+		node.Children = nil
+		fs.numFiles = 0
+		return nil
+	}
+	nodePath := node.Path()
+	fs.recordRemoved(node)
+	if err := node.Unlink(); err != nil {
+		return err
+	}
+	fs.numFiles -= countFiles(node)
+	fs.watchers.emit(nodePath, OpRemove)
+	return nil
+}
+
+// Rename moves oldName to newName by re-linking its node, so that no file
+// contents are copied and renaming a large file is O(1). Missing parent
+// directories of newName are created. A file replaces an existing file, and
+// a directory replaces an existing empty directory. A symbolic link is
+// renamed itself rather than its target.
+func (fs *MemFS) Rename(oldName, newName string) error {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+
+	oldPath, err := memPath("rename", oldName)
+	if err != nil {
+		return err
+	}
+	newPath, err := memPath("rename", newName)
+	if err != nil {
+		return err
+	}
+	node, err := fs.root.Lookup(false, oldPath...)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return notFound("rename", oldName)
+	}
+	if node == fs.root {
+		return fmt.Errorf("cannot rename the root directory")
+	}
+	if len(newPath) == 1 && newPath[0] == "." {
+		return fmt.Errorf("cannot rename '%s' to the root directory", oldName)
+	}
+	parentPath, base := newPath[:len(newPath)-1], newPath[len(newPath)-1]
+	dir, err := fs.deepestDir(parentPath...)
+	if err == ErrNotDir {
+		return fmt.Errorf("cannot rename '%s' to '%s'. Parent is not a directory", oldName, newName)
+	} else if err != nil {
+		return err
+	}
+	for ancestor := dir; ancestor != nil; ancestor = ancestor.Parent {
+		if ancestor == node {
+			return fmt.Errorf("cannot rename '%s' to '%s'. Path is inside the directory", oldName, newName)
+		}
+	}
+	var existing *dirNode
+	if parent := fs.root.Get(parentPath...); parent != nil {
+		existing = parent.Children.Get(base)
+	}
+	if existing == node {
+		return nil
+	} else if existing != nil {
+		switch {
+		case existing.IsDirectory() && !node.IsDirectory():
+			return fmt.Errorf("cannot rename '%s' to '%s'. Path is a directory", oldName, newName)
+		case !existing.IsDirectory() && node.IsDirectory():
+			return fmt.Errorf("cannot rename '%s' to '%s'. Path is not a directory", oldName, newName)
+		case existing.IsDirectory() && len(existing.Children) > 0:
+			return fmt.Errorf("cannot rename '%s' to '%s'. Directory is not empty", oldName, newName)
+		}
+	}
+
+	// The missing parents are only created once the rename is known to
+	// succeed, so that a failed rename leaves fs unchanged.
+	parent := fs.root.GetOrAdd(nil, parentPath...)
+	if parent == nil {
+		return fmt.Errorf("cannot rename '%s' to '%s'. Parent is a dangling symbolic link", oldName, newName)
+	}
+	if existing != nil {
+		fs.recordRemoved(existing)
+		if err := existing.Unlink(); err != nil {
+			return err
+		}
+		fs.numFiles -= countFiles(existing)
+	}
+
+	nodePath := node.Path()
+	fs.recordRemoved(node)
+	if err := node.relink(parent, base); err != nil {
+		return err
+	}
+	fs.watchers.emit(nodePath, OpRename)
+	fs.watchers.emit(node.Path(), OpRename)
+	return nil
+}
+
+// MoveMerge moves src to dst like Rename, except that moving a directory onto
+// an existing directory merges them, like "mv src/* dst/" does: every file in
+// src is moved to the same relative path in dst, replacing a file or symbolic
+// link that is already there, and the subdirectories of src are merged into
+// those of dst in the same way. Files in dst that are not in src are kept.
+//
+// A file and a directory at the same relative path conflict. MoveMerge checks
+// for conflicts before moving anything, and returns an error if it finds one,
+// leaving fs unchanged. As with Rename, no contents are copied.
+func (fs *MemFS) MoveMerge(src, dst string) error {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+
+	srcPath, err := memPath("move", src)
+	if err != nil {
+		return err
+	}
+	dstPath, err := memPath("move", dst)
+	if err != nil {
+		return err
+	}
+	node, err := fs.root.Lookup(false, srcPath...)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return notFound("move", src)
+	}
+	if node == fs.root {
+		return fmt.Errorf("cannot move the root directory")
+	}
+	target, err := fs.root.Lookup(false, dstPath...)
+	if err != nil {
+		return err
+	}
+	if target == node {
+		return nil
+	}
+	for ancestor := target; ancestor != nil; ancestor = ancestor.Parent {
+		if ancestor == node {
+			return fmt.Errorf("cannot move '%s' to '%s'. Path is inside the directory", src, dst)
+		}
+	}
+	if err := checkMerge(node, target); err != nil {
+		return err
+	}
+	var parent *dirNode
+	if target != fs.root {
+		parentPath := dstPath[:len(dstPath)-1]
+		dir, err := fs.deepestDir(parentPath...)
+		if err == ErrNotDir {
+			return fmt.Errorf("cannot move '%s' to '%s'. Parent is not a directory", src, dst)
+		} else if err != nil {
+			return err
+		}
+		for ancestor := dir; ancestor != nil; ancestor = ancestor.Parent {
+			if ancestor == node {
+				return fmt.Errorf("cannot move '%s' to '%s'. Path is inside the directory", src, dst)
+			}
+		}
+		// The missing parents are only created once the move is known to
+		// succeed, so that a failed move leaves fs unchanged.
+		if parent = fs.root.GetOrAdd(nil, parentPath...); parent == nil {
+			return fmt.Errorf("cannot move '%s' to '%s'. Parent is a dangling symbolic link", src, dst)
+		}
+	}
+
+	oldPath := node.Path()
+	if target == fs.root {
+		fs.mergeDir(node, target)
 	} else {
-		return &memFile{name: name, buf: bytes.NewBuffer(node.B)}, nil
+		fs.moveInto(node, parent, dstPath[len(dstPath)-1])
+	}
+	fs.watchers.emit(oldPath, OpRename)
+	fs.watchers.emit(CleanPath(dst), OpRename)
+	return nil
+}
+
+// deepestDir returns the deepest directory along path that exists, which is
+// the directory at path itself if it exists, without creating any. Symbolic
+// links are followed. It returns ErrNotDir if an element of path is a file.
+// The caller must hold the lock.
+func (fs *MemFS) deepestDir(path ...string) (*dirNode, error) {
+	dir := fs.root
+	for i := 1; i <= len(path); i++ {
+		node, err := fs.root.Lookup(true, path[:i]...)
+		if err != nil {
+			return nil, err
+		}
+		if node == nil {
+			break
+		}
+		if !node.IsDirectory() {
+			return nil, ErrNotDir
+		}
+		dir = node
+	}
+	return dir, nil
+}
+
+// checkMerge returns an error if moving node onto target with MoveMerge would
+// put a file where there is a directory, or the other way around. A nil
+// target does not conflict.
+func checkMerge(node, target *dirNode) error {
+	switch {
+	case target == nil:
+		return nil
+	case node.IsDirectory() && target.IsDirectory():
+		for _, child := range node.Children {
+			if err := checkMerge(child, target.Children.Get(child.Name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case target.IsDirectory():
+		return fmt.Errorf("cannot move '%s' to '%s'. Path is a directory", node.Path(), pathOrRoot(target))
+	case node.IsDirectory():
+		return fmt.Errorf("cannot move '%s' to '%s'. Path is not a directory", node.Path(), target.Path())
+	}
+	return nil
+}
+
+// pathOrRoot returns the path of node, or "." for the root.
+func pathOrRoot(node *dirNode) string {
+	if node.Parent == nil {
+		return "."
+	}
+	return node.Path()
+}
+
+// moveInto moves node to the child base of parent, merging it into an
+// existing directory and replacing an existing file. The caller must have
+// checked for conflicts with checkMerge and hold the write lock.
+func (fs *MemFS) moveInto(node, parent *dirNode, base string) {
+	existing := parent.Children.Get(base)
+	switch {
+	case existing == nil:
+		fs.recordRemoved(node)
+		_ = node.relink(parent, base)
+	case existing.IsDirectory():
+		fs.mergeDir(node, existing)
+	default:
+		fs.recordRemoved(existing)
+		_ = existing.Unlink()
+		fs.numFiles -= countFiles(existing)
+		fs.recordRemoved(node)
+		_ = node.relink(parent, base)
 	}
 }
 
+// mergeDir moves the children of the directory node into dir with moveInto,
+// and removes node.
+func (fs *MemFS) mergeDir(node, dir *dirNode) {
+	for _, child := range append(dirNodeSlice(nil), node.Children...) {
+		fs.moveInto(child, dir, child.Name)
+	}
+	_ = node.Unlink()
+}
+
+// Watch implements Watcher. Events are emitted when writers returned by
+// Create and Append are closed, and on Remove and RemoveAll. Removing a
+// directory emits a single event for the directory. name is cleaned with
+// CleanPath, so "./dir/" watches the same paths as "dir".
+func (fs *MemFS) Watch(name string) (<-chan Event, func(), error) {
+	fs.init()
+	var path string
+	if name = CleanPath(name); name != "." {
+		path = name
+	}
+	ch, stop := fs.watchers.add(path)
+	return ch, stop, nil
+}
+
+// Readlink returns the target of the named symbolic link.
+func (fs *MemFS) Readlink(name string) (string, error) {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+	path, err := memPath("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	node, err := fs.root.Lookup(false, path...)
+	if err != nil {
+		return "", err
+	}
+	if node == nil {
+		return "", notFound("readlink", name)
+	}
+	if !node.IsLink() {
+		return "", fmt.Errorf("cannot read link '%s'. Path is not a symbolic link", name)
+	}
+	return node.LinkTarget, nil
+}
+
+// ReadFileNames returns the sorted base names of the entries of dir that are
+// not directories. Unlike ListFiles it behaves the same for OsFS.
+func (fs *MemFS) ReadFileNames(dir string) ([]string, error) {
+	return readFileNames(fs, dir)
+}
+
+// ListFiles returns the paths of all files and directories in the tree
+// rooted at dir, including dir itself, in depth-first order. The paths start
+// with a slash, as in "/dir/file", and the root is returned as "".
+//
+// Deprecated: the ListFiles method of OsFS only lists the files directly in
+// dir, by base name, so code using ListFiles behaves differently depending on
+// the FS. Use ReadFileNames for the files directly in dir, or WalkFiles or
+// EachFile for the whole tree.
 func (fs *MemFS) ListFiles(dir string) ([]string, error) {
 	fs.init()
 	fs.l.RLock()
 	defer fs.l.RUnlock()
 
-	node := fs.root.Get(nameToPath(dir)...)
+	path, err := memPath("listfiles", dir)
+	if err != nil {
+		return nil, err
+	}
+	node := fs.root.Get(path...)
 
-	if node != nil && !node.IsDirectory() {
-		return nil, ErrNotFound // If dir a file, return ErrNotFound
+	if node == nil || !node.IsDirectory() {
+		return nil, notFound("listfiles", dir) // If dir a file, return ErrNotFound
 	}
 
 	var names []string
 	node.DFS(func(node *dirNode) {
-		names = append(names, node.Path())
+		// ListFiles keeps its original format, in which paths start with
+		// a slash and the root is "".
+		var name string
+		if node.Parent != nil {
+			name = "/" + node.Path()
+		}
+		names = append(names, name)
+	})
+
+	return names, nil
+}
+
+// EachFile calls fn with the path of every file beneath dir, in depth-first
+// order, without collecting the paths first. Directories and symbolic links
+// are not passed to fn. If fn returns an error, no further calls are made and
+// the error is returned. It returns ErrNotFound if dir is missing or is a
+// file.
+//
+// The read lock of fs is held while fn runs, so fn must not modify fs.
+func (fs *MemFS) EachFile(dir string, fn func(path string) error) error {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+
+	path, err := memPath("eachfile", dir)
+	if err != nil {
+		return err
+	}
+	node := fs.root.Get(path...)
+	if node == nil || !node.IsDirectory() {
+		return notFound("eachfile", dir)
+	}
+
+	node.DFS(func(n *dirNode) {
+		if err == nil && !n.IsDirectory() && !n.IsLink() {
+			err = fn(n.Path())
+		}
+	})
+	return err
+}
+
+// ListDirs returns the paths of all directories beneath dir, not including
+// dir itself, in depth-first order.
+func (fs *MemFS) ListDirs(dir string) ([]string, error) {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+
+	path, err := memPath("listdirs", dir)
+	if err != nil {
+		return nil, err
+	}
+	node := fs.root.Get(path...)
+
+	if node == nil || !node.IsDirectory() {
+		return nil, notFound("listdirs", dir)
+	}
+
+	var names []string
+	node.DFS(func(n *dirNode) {
+		if n != node && n.IsDirectory() {
+			names = append(names, n.Path())
+		}
 	})
 
 	return names, nil
@@ -106,28 +930,252 @@ func (fs *MemFS) ReadDir(dir string) ([]DirEntry, error) {
 	fs.l.RLock()
 	defer fs.l.RUnlock()
 
-	node := fs.root.Get(nameToPath(dir)...)
+	path, err := memPath("readdir", dir)
+	if err != nil {
+		return nil, err
+	}
+	node := fs.root.Get(path...)
 
 	if node == nil || !node.IsDirectory() {
-		return nil, ErrNotFound // If dir a file, return ErrNotFound
+		return nil, notFound("readdir", dir) // If dir a file, return ErrNotFound
 	}
 
 	entries := make([]DirEntry, len(node.Children))
 	for i, child := range node.Children {
-		entries[i] = &dirEntry{name: child.Name, isDir: child.IsDirectory()}
+		entries[i] = newDirEntry(child.FileInfo())
 	}
-	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	sortDirEntries(entries)
 
 	return entries, nil
 }
 
+// DiskUsage returns the total size of the files in the tree rooted at dir.
+// Directories contribute zero bytes themselves, and symbolic links are not
+// followed. It returns ErrNotFound if dir is missing or is a file.
+func (fs *MemFS) DiskUsage(dir string) (int64, error) {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+
+	path, err := memPath("diskusage", dir)
+	if err != nil {
+		return 0, err
+	}
+	node := fs.root.Get(path...)
+	if node == nil || !node.IsDirectory() {
+		return 0, notFound("diskusage", dir)
+	}
+
+	var n int64
+	node.DFS(func(node *dirNode) {
+		n += int64(len(node.B))
+	})
+	return n, nil
+}
+
+// Size returns the total number of bytes of file contents in fs.
+func (fs *MemFS) Size() int64 {
+	var n int64
+	fs.walk(func(node *dirNode) {
+		n += int64(len(node.B))
+	})
+	return n
+}
+
+// NumFiles returns the number of files in fs. Symbolic links count as files.
+func (fs *MemFS) NumFiles() int {
+	var n int
+	fs.walk(func(node *dirNode) {
+		if !node.IsDirectory() {
+			n++
+		}
+	})
+	return n
+}
+
+// NumDirs returns the number of directories in fs, not counting the root.
+func (fs *MemFS) NumDirs() int {
+	var n int
+	fs.walk(func(node *dirNode) {
+		if node.IsDirectory() && node != fs.root {
+			n++
+		}
+	})
+	return n
+}
+
+// walk calls fn for every node in the tree under the read lock.
+func (fs *MemFS) walk(fn func(node *dirNode)) {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+	fs.root.DFS(fn)
+}
+
+// DirtyFiles returns the paths of all files that have been written to since
+// the last call to DirtyFiles (or MarkClean), and marks them as clean.
+func (fs *MemFS) DirtyFiles() []string {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	var names []string
+	fs.root.DFS(func(node *dirNode) {
+		if node.Dirty && !node.IsDirectory() {
+			names = append(names, node.Path())
+			node.Dirty = false
+		}
+	})
+	return names
+}
+
+// MarkClean clears the dirty flag of the given files. Paths that do not exist
+// are ignored.
+func (fs *MemFS) MarkClean(paths ...string) {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	for _, name := range paths {
+		path, err := memPath("markclean", name)
+		if err != nil {
+			continue
+		}
+		if node := fs.root.Get(path...); node != nil {
+			node.Dirty = false
+		}
+	}
+}
+
+// RemovedFiles returns the sorted paths of the files and symbolic links that
+// have been removed since the last call to RemovedFiles, including the old
+// paths of renamed files and files replaced by Rename or MoveMerge, and
+// forgets them. Paths at which a file exists again are left out, since
+// DirtyFiles reports them. Together with DirtyFiles it describes the changes
+// to apply to a copy of fs that is synced by writing back dirty files.
+func (fs *MemFS) RemovedFiles() []string {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	var names []string
+	for name := range fs.removed {
+		if node, _ := fs.root.Lookup(false, nameToPath(name)...); node == nil || node.IsDirectory() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	fs.removed = nil
+	return names
+}
+
+// recordRemoved records the paths of the files and symbolic links in the tree
+// rooted at node for RemovedFiles. It must be called before node is unlinked
+// or moved. The caller must hold the write lock.
+func (fs *MemFS) recordRemoved(node *dirNode) {
+	node.DFS(func(n *dirNode) {
+		if !n.IsDirectory() {
+			if fs.removed == nil {
+				fs.removed = map[string]bool{}
+			}
+			fs.removed[n.Path()] = true
+		}
+	})
+}
+
+// Reset removes all files and directories, leaving fs empty. Watchers stay
+// subscribed but are not notified.
+func (fs *MemFS) Reset() {
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	if fs.root != nil {
+		fs.recordRemoved(fs.root)
+	}
+	fs.root = &dirNode{}
+	fs.numFiles = 0
+}
+
+// Compact reallocates the contents of every file to a slice of exactly its
+// length, releasing the spare capacity left behind by Append. Readers that
+// opened a file before Compact keep a reference to its old array, so the
+// memory is only reclaimed once no reader references the array any more and
+// it is garbage collected; closing a reader does not release it. Later
+// readers no longer share an array with them. Run it when fs is quiescent,
+// for example after a batch of appends.
+func (fs *MemFS) Compact() {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	fs.root.DFS(func(node *dirNode) {
+		if node.B != nil && cap(node.B) > len(node.B) {
+			b := make([]byte, len(node.B))
+			copy(b, node.B)
+			node.B = b
+		}
+	})
+}
+
+// Fork returns a copy of fs that shares the contents of its files with fs.
+// Only the directory tree is copied, so forking is cheap even when the files
+// are large. Since the contents of a file are never modified in place, a
+// write to either file system gives that file new contents, leaving the other
+// unchanged: writing to the fork does not affect fs, and writing to fs after
+// Fork does not affect the fork. The first Append to a shared file in the
+// fork copies its contents. The fork has the same file limit as fs, and no
+// watchers.
+func (fs *MemFS) Fork() *MemFS {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+	return &MemFS{root: fs.root.fork(nil), numFiles: fs.numFiles, maxFiles: fs.maxFiles}
+}
+
+// memWriter is the writer returned by MemFS.Create and Append. Writes are
+// buffered and committed to the tree on Close.
+type memWriter struct {
+	writeCloser
+}
+
+// memWriterBuffers holds the buffers of closed memWriters for reuse, so that
+// writing many files does not allocate and grow a buffer for each.
+var memWriterBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// maxPooledBuffer is the largest capacity of a buffer that is returned to
+// memWriterBuffers. Larger buffers are left to the garbage collector, so
+// that one large file does not keep its memory pinned in the pool.
+const maxPooledBuffer = 64 << 10
+
+// newMemWriter returns a memWriter that buffers writes in a pooled buffer,
+// and on Close passes the contents to commit before returning the buffer to
+// the pool. commit must copy the bytes it keeps, since the buffer is reused.
+func newMemWriter(commit func(b []byte) error) *memWriter {
+	buf := memWriterBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	return &memWriter{writeCloser{w: buf, closeFn: func() error {
+		err := commit(buf.Bytes())
+		if buf.Cap() <= maxPooledBuffer {
+			memWriterBuffers.Put(buf)
+		}
+		return err
+	}}}
+}
+
+// Sync implements Syncer. It does nothing, since there is no storage to
+// commit to before Close.
+func (w *memWriter) Sync() error {
+	return nil
+}
+
+// memFile is a file opened with MemFS.Open. The embedded bytes.Reader makes
+// it an io.ReadSeeker and io.ReaderAt, so that callers such as
+// http.ServeContent can serve ranges without reading sequentially. ReadAt
+// does not use the read offset, so it is safe to call concurrently on one
+// handle, and a read past the end returns io.EOF with a short count.
 type memFile struct {
+	*bytes.Reader
 	name string
-	buf  *bytes.Buffer
+	info *fileInfo
 }
 
-func (f *memFile) Read(p []byte) (n int, err error) {
-	return f.buf.Read(p)
+func (f *memFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
 }
 
 func (f *memFile) Close() error {
@@ -135,17 +1183,22 @@ func (f *memFile) Close() error {
 }
 
 func (f *memFile) ReadDir(n int) ([]DirEntry, error) {
-	return nil, fmt.Errorf("cannot ReadDir '%s'. Path is a file", f.name)
+	return nil, &FSError{Op: "readdir", Path: f.name, Err: ErrNotDir}
 }
 
 type memDir struct {
 	fs             *MemFS
 	name           string
+	info           *fileInfo
 	readDirEntries []DirEntry
 }
 
+func (dir *memDir) Stat() (os.FileInfo, error) {
+	return dir.info, nil
+}
+
 func (dir *memDir) Read(p []byte) (n int, err error) {
-	return 0, fmt.Errorf("cannot read '%s'. Path is a directory", dir.name)
+	return 0, &FSError{Op: "read", Path: dir.name, Err: ErrIsDir}
 }
 
 func (dir *memDir) Close() error {
@@ -160,30 +1213,23 @@ func (dir *memDir) ReadDir(n int) ([]DirEntry, error) {
 		}
 		dir.readDirEntries = entries
 	}
-
-	if len(dir.readDirEntries) == 0 {
-		if n < 0 {
-			return dir.readDirEntries, nil
-		}
-		return dir.readDirEntries, io.EOF
-	}
-
-	size := n
-	if size < 0 || size > len(dir.readDirEntries) {
-		size = len(dir.readDirEntries)
-	}
-
-	entries := dir.readDirEntries[:size]
-	dir.readDirEntries = dir.readDirEntries[size:]
-
-	return entries, nil
+	return nextDirEntries(&dir.readDirEntries, n)
 }
 
+// maxLinkHops is the maximum number of symbolic links followed while
+// resolving a single path. Exceeding it is treated as a link cycle.
+const maxLinkHops = 40
+
 type dirNode struct {
-	Name     string
-	Parent   *dirNode
-	Children dirNodeSlice
-	B        []byte
+	Name       string
+	Parent     *dirNode
+	Children   dirNodeSlice
+	B          []byte
+	LinkTarget string
+	Mode       os.FileMode
+	ModeSet    bool // Mode was given explicitly, so a zero Mode is not the default
+	ModTime    time.Time
+	Dirty      bool
 }
 
 func (node *dirNode) Level() int {
@@ -197,12 +1243,50 @@ func (node *dirNode) Level() int {
 }
 
 func (node *dirNode) IsDirectory() bool {
-	return node.B == nil
+	return node.B == nil && node.LinkTarget == ""
+}
+
+func (node *dirNode) IsLink() bool {
+	return node.LinkTarget != ""
+}
+
+func (node *dirNode) Root() *dirNode {
+	for node.Parent != nil {
+		node = node.Parent
+	}
+	return node
+}
+
+func (node *dirNode) FileInfo() *fileInfo {
+	info := &fileInfo{name: node.Name, size: int64(len(node.B)), isDir: node.IsDirectory(), mode: node.Mode, modTime: node.ModTime}
+	if info.isDir {
+		info.mode = os.ModeDir | 0777
+	} else if node.IsLink() {
+		info.mode = os.ModeSymlink | 0777
+		info.size = int64(len(node.LinkTarget))
+	} else if info.mode == 0 && !node.ModeSet {
+		info.mode = defaultFileMode
+	}
+	return info
 }
 
 func (node *dirNode) Get(path ...string) *dirNode {
+	got, _ := node.Lookup(true, path...)
+	return got
+}
+
+// Lookup returns the node at the given path relative to node, or nil if there
+// is no such node. An empty path refers to node itself. Symbolic links in all but the last path element are
+// followed, and the last one is followed if follow is true. ErrTooManyLinks
+// is returned if resolving the path follows more than maxLinkHops links.
+func (node *dirNode) Lookup(follow bool, path ...string) (*dirNode, error) {
+	var hops int
+	return node.lookup(follow, &hops, path...)
+}
+
+func (node *dirNode) lookup(follow bool, hops *int, path ...string) (*dirNode, error) {
 	if len(path) == 0 {
-		panic(":(")
+		return node, nil
 	}
 	var next *dirNode
 	p := path[0]
@@ -214,32 +1298,64 @@ func (node *dirNode) Get(path ...string) *dirNode {
 	default:
 		next = node.Children.Get(p)
 	}
+	if next != nil && next.IsLink() && (follow || len(path) > 1) {
+		var err error
+		if next, err = next.resolveLink(hops); err != nil {
+			return nil, err
+		}
+	}
 	if next == nil {
-		return nil
+		return nil, nil
 	}
 	if len(path) > 1 {
-		return next.Get(path[1:]...)
+		return next.lookup(follow, hops, path[1:]...)
 	}
-	return next
+	return next, nil
+}
+
+func (node *dirNode) resolveLink(hops *int) (*dirNode, error) {
+	*hops++
+	if *hops > maxLinkHops {
+		return nil, ErrTooManyLinks
+	}
+	start, target := node.Parent, node.LinkTarget
+	if strings.HasPrefix(target, "/") {
+		start, target = node.Root(), strings.TrimPrefix(target, "/")
+	}
+	return start.lookup(true, hops, nameToPath(target)...)
 }
 
 func (node *dirNode) Path() string {
-	if node.Parent == nil {
+	if node.Parent == nil || node.Parent.Parent == nil {
 		return node.Name
 	}
 	return node.Parent.Path() + "/" + node.Name
 }
 
+// AddDescendant returns the node at the given path, adding it and any missing
+// parent directories. Symbolic links to directories are followed. It returns
+// nil if the path cannot be created because a link along it is dangling, or
+// because it has a ".." element.
 func (node *dirNode) AddDescendant(b []byte, path ...string) *dirNode {
 	childName := path[0]
+	if childName == ".." {
+		return nil
+	}
+	child := node.Children.Get(childName)
+	if child != nil && child.IsLink() {
+		if len(path) == 1 {
+			return nil
+		}
+		if child = child.Get("."); child == nil {
+			return nil
+		}
+	}
 	if len(path) > 1 {
-		child := node.Children.Get(childName)
 		if child == nil {
 			child = node.AddChild(childName, nil)
 		}
 		return child.AddDescendant(b, path[1:]...)
 	}
-	child := node.Children.Get(childName)
 	if child == nil {
 		child = node.AddChild(childName, b)
 	}
@@ -247,12 +1363,46 @@ func (node *dirNode) AddDescendant(b []byte, path ...string) *dirNode {
 }
 
 func (node *dirNode) AddChild(name string, b []byte) *dirNode {
-	child := &dirNode{Name: name, Parent: node, B: b}
+	child := &dirNode{Name: name, Parent: node, B: b, ModTime: time.Now()}
 	node.Children = append(node.Children, child)
 	sort.Sort(node.Children)
 	return child
 }
 
+// relink moves node to the child base of parent, which must not exist, and
+// marks the files beneath it dirty.
+func (node *dirNode) relink(parent *dirNode, base string) error {
+	if err := node.Unlink(); err != nil {
+		return err
+	}
+	node.Name = base
+	node.Parent = parent
+	parent.Children = append(parent.Children, node)
+	sort.Sort(parent.Children)
+	node.DFS(func(n *dirNode) {
+		if !n.IsDirectory() {
+			n.Dirty = true
+		}
+	})
+	return nil
+}
+
+// Unlink removes node from its parent.
+func (node *dirNode) Unlink() error {
+	parent := node.Parent
+	if parent == nil {
+		return fmt.Errorf("cannot remove the root directory")
+	}
+	for i, child := range parent.Children {
+		if child == node {
+			parent.Children = append(parent.Children[:i:i], parent.Children[i+1:]...)
+			break
+		}
+	}
+	node.Parent = nil
+	return nil
+}
+
 func (node *dirNode) GetOrAdd(b []byte, path ...string) *dirNode {
 	if got := node.Get(path...); got != nil {
 		return got
@@ -267,6 +1417,25 @@ func (node *dirNode) DFS(fn func(node *dirNode)) {
 	}
 }
 
+// fork returns a copy of the tree rooted at node, with parent as its parent,
+// that shares the contents of the files. The capacity of the shared slices
+// is limited to their length, so that appending to them in the copy
+// reallocates rather than writing to an array shared with node.
+func (node *dirNode) fork(parent *dirNode) *dirNode {
+	cp := *node
+	cp.Parent = parent
+	if cp.B != nil {
+		cp.B = cp.B[:len(cp.B):len(cp.B)]
+	}
+	if node.Children != nil {
+		cp.Children = make(dirNodeSlice, len(node.Children))
+		for i, child := range node.Children {
+			cp.Children[i] = child.fork(&cp)
+		}
+	}
+	return &cp
+}
+
 func (node *dirNode) String() string {
 	var sb strings.Builder
 	node.DFS(func(child *dirNode) {
@@ -298,14 +1467,36 @@ func (s dirNodeSlice) Get(name string) *dirNode {
 	return nil
 }
 
+// nameToPath splits name into its path elements. The name is cleaned first,
+// so leading, trailing and repeated slashes and "." elements are ignored, and
+// "a/../b" is the same as "b". The root may be given as "", "." or "/", and
+// is returned as ["."]. Leading ".." elements are kept, since relative link
+// targets may refer to the parent of the link's directory. Names passed to
+// MemFS methods are split with memPath instead.
 func nameToPath(name string) []string {
+	name = CleanPath(name)
+	if name == "." {
+		return []string{"."}
+	}
 	return strings.Split(name, "/")
 }
 
-func getBytes(buf *bytes.Buffer) []byte {
-	b := buf.Bytes()
-	if b != nil {
-		return b
+// memPath is like nameToPath, but cleans name with CleanLocalPath, returning
+// an error wrapping ErrPathEscape if it refers to a path outside the root,
+// such as "../a", rather than keeping the leading ".." elements.
+func memPath(op, name string) ([]string, error) {
+	clean, err := CleanLocalPath(name)
+	if err != nil {
+		return nil, &FSError{Op: op, Path: name, Err: ErrPathEscape}
 	}
-	return make([]byte, 0)
+	if clean == "." {
+		return []string{"."}, nil
+	}
+	return strings.Split(clean, "/"), nil
+}
+
+// cloneBytes returns a copy of b with no spare capacity. The copy is never
+// nil, even if b is, since a nil slice would make a node a directory.
+func cloneBytes(b []byte) []byte {
+	return append(make([]byte, 0, len(b)), b...)
 }
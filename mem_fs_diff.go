@@ -0,0 +1,112 @@
+package simplefs
+
+import (
+	"bytes"
+	"unsafe"
+)
+
+// SnapshotDiff compares two MemFS instances and classifies every file path
+// as added (only in after), modified (in both, with differing contents) or
+// removed (only in before). Both trees are walked once, side by side, and
+// file contents are compared byte by byte. Directories are not reported
+// themselves, only the files within them.
+func SnapshotDiff(before, after *MemFS) (added, modified, removed []string) {
+	if before == after {
+		return nil, nil, nil
+	}
+	defer rlockPair(before, after)()
+
+	var d snapshotDiff
+	d.compare(before.root, after.root)
+	return d.added, d.modified, d.removed
+}
+
+// Equal reports whether fs and other have the same directory structure, and
+// the same contents in every file. File modes and dirty flags are ignored.
+func (fs *MemFS) Equal(other *MemFS) bool {
+	if fs == other {
+		return true
+	}
+	defer rlockPair(fs, other)()
+	return fs.root.Equal(other.root)
+}
+
+// rlockPair read-locks two distinct MemFS instances and returns a function
+// that unlocks them. The locks are taken in order of address rather than
+// argument order, so that concurrent calls with the arguments swapped cannot
+// deadlock with each other while a writer is waiting on either.
+func rlockPair(a, b *MemFS) (unlock func()) {
+	a.init()
+	b.init()
+	if uintptr(unsafe.Pointer(b)) < uintptr(unsafe.Pointer(a)) {
+		a, b = b, a
+	}
+	a.l.RLock()
+	b.l.RLock()
+	return func() {
+		b.l.RUnlock()
+		a.l.RUnlock()
+	}
+}
+
+type snapshotDiff struct {
+	added, modified, removed []string
+}
+
+func (d *snapshotDiff) compare(before, after *dirNode) {
+	b, a := before.Children, after.Children
+	for len(b) > 0 || len(a) > 0 {
+		switch {
+		case len(a) == 0 || (len(b) > 0 && b[0].Name < a[0].Name):
+			d.removed = appendFilePaths(d.removed, b[0])
+			b = b[1:]
+		case len(b) == 0 || a[0].Name < b[0].Name:
+			d.added = appendFilePaths(d.added, a[0])
+			a = a[1:]
+		default:
+			d.compareNode(b[0], a[0])
+			b, a = b[1:], a[1:]
+		}
+	}
+}
+
+func (d *snapshotDiff) compareNode(before, after *dirNode) {
+	switch {
+	case before.IsDirectory() && after.IsDirectory():
+		d.compare(before, after)
+	case !before.IsDirectory() && !after.IsDirectory():
+		if !bytes.Equal(before.B, after.B) || before.LinkTarget != after.LinkTarget {
+			d.modified = append(d.modified, after.Path())
+		}
+	default:
+		d.removed = appendFilePaths(d.removed, before)
+		d.added = appendFilePaths(d.added, after)
+	}
+}
+
+// appendFilePaths appends the paths of all files in the subtree rooted at node.
+func appendFilePaths(paths []string, node *dirNode) []string {
+	node.DFS(func(node *dirNode) {
+		if !node.IsDirectory() {
+			paths = append(paths, node.Path())
+		}
+	})
+	return paths
+}
+
+// Equal reports whether the trees rooted at node and other have the same
+// names, types and contents.
+func (node *dirNode) Equal(other *dirNode) bool {
+	if node.Name != other.Name || node.IsDirectory() != other.IsDirectory() || node.LinkTarget != other.LinkTarget {
+		return false
+	}
+	if !bytes.Equal(node.B, other.B) || len(node.Children) != len(other.Children) {
+		return false
+	}
+	for i := range node.Children {
+		if !node.Children[i].Equal(other.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
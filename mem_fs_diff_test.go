@@ -0,0 +1,132 @@
+package simplefs
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	before := &MemFS{}
+	before.SetString("same", "same")
+	before.SetString("changed", "old")
+	before.SetString("removed", "removed")
+	before.SetString("dir/removed", "removed")
+	before.SetString("dir/same", "same")
+	before.SetString("became-dir", "file")
+	before.SetString("became-file/a", "a")
+
+	after := &MemFS{}
+	after.SetString("same", "same")
+	after.SetString("changed", "new")
+	after.SetString("added", "added")
+	after.SetString("dir/same", "same")
+	after.SetString("dir/sub/added", "added")
+	after.SetString("became-dir/b", "b")
+	after.SetString("became-file", "file")
+
+	added, modified, removed := SnapshotDiff(before, after)
+	assert := func(what string, got []string, want ...string) {
+		t.Helper()
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("%s: got %v, want %v", what, got, want)
+		}
+	}
+	assert("added", added, "added", "became-dir/b", "became-file", "dir/sub/added")
+	assert("modified", modified, "changed")
+	assert("removed", removed, "became-dir", "became-file/a", "dir/removed", "removed")
+}
+
+func TestMemFSEqual(t *testing.T) {
+	newFS := func() *MemFS {
+		fs := &MemFS{}
+		fs.SetString("b", "b")
+		fs.SetString("a/c", "c")
+		fs.SetString("a/empty", "")
+		return fs
+	}
+	fs := newFS()
+	if !fs.Equal(fs) || !fs.Equal(newFS()) {
+		t.Fatalf("Equal() returned false for equal trees")
+	}
+
+	// Insertion order does not matter
+	other := &MemFS{}
+	other.SetString("a/empty", "")
+	other.SetString("a/c", "c")
+	other.SetString("b", "b")
+	if !fs.Equal(other) {
+		t.Fatalf("Equal() returned false for trees built in a different order")
+	}
+
+	changes := map[string]func(fs *MemFS){
+		"different contents": func(fs *MemFS) { fs.SetString("a/c", "C") },
+		"extra file":         func(fs *MemFS) { fs.SetString("a/d", "") },
+		"removed file":       func(fs *MemFS) { _ = fs.Remove("b") },
+		"dir instead of file": func(fs *MemFS) {
+			_ = fs.Remove("b")
+			fs.SetString("b/c", "c")
+		},
+	}
+	for name, change := range changes {
+		other := newFS()
+		change(other)
+		if fs.Equal(other) || other.Equal(fs) {
+			t.Fatalf("%s: Equal() returned true", name)
+		}
+	}
+}
+
+func TestMemFSEqualConcurrentSwapped(t *testing.T) {
+	a, b := &MemFS{}, &MemFS{}
+	a.SetString("file", "x")
+	b.SetString("file", "x")
+
+	// Comparing in both orders while both file systems are written to must
+	// not deadlock.
+	var wg sync.WaitGroup
+	for _, pair := range [][2]*MemFS{{a, b}, {b, a}} {
+		pair := pair
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				pair[0].Equal(pair[1])
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				pair[0].SetString("file", "x")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSnapshotDiffConcurrentSwapped(t *testing.T) {
+	a, b := &MemFS{}, &MemFS{}
+	a.SetString("file", "x")
+	b.SetString("file", "y")
+
+	// Diffing in both orders while both file systems are written to must not
+	// deadlock.
+	var wg sync.WaitGroup
+	for _, pair := range [][2]*MemFS{{a, b}, {b, a}} {
+		pair := pair
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				SnapshotDiff(pair[0], pair[1])
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				pair[0].SetString("other", "z")
+			}
+		}()
+	}
+	wg.Wait()
+}
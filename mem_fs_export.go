@@ -0,0 +1,151 @@
+package simplefs
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExportTo writes the whole tree to the OS directory dir, creating it if
+// needed. Existing files are overwritten, but other files in dir are left
+// alone. Empty directories, file modes and modification times are
+// preserved. Symbolic links are written as links; a target that is absolute
+// within the MemFS is rewritten relative to the link, so that the link
+// resolves inside dir.
+func (fs *MemFS) ExportTo(dir string) error {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	var err error
+	fs.root.DFS(func(node *dirNode) {
+		if err != nil || node == fs.root {
+			return
+		}
+		p := filepath.Join(dir, filepath.FromSlash(node.Path()))
+		switch {
+		case node.IsDirectory():
+			err = os.MkdirAll(p, 0777)
+		case node.IsLink():
+			target := node.LinkTarget
+			if strings.HasPrefix(target, "/") {
+				target, err = filepath.Rel(filepath.Dir(p), filepath.Join(dir, filepath.FromSlash(target)))
+				if err != nil {
+					return
+				}
+			}
+			if err = os.Remove(p); err != nil && !os.IsNotExist(err) {
+				return
+			}
+			err = os.Symlink(target, p)
+		default:
+			info := node.FileInfo()
+			if err = os.WriteFile(p, node.B, info.Mode().Perm()); err != nil {
+				return
+			}
+			if err = os.Chmod(p, info.Mode().Perm()); err != nil {
+				return
+			}
+			if !node.ModTime.IsZero() {
+				err = os.Chtimes(p, node.ModTime, node.ModTime)
+			}
+		}
+	})
+	return err
+}
+
+// ImportFrom loads the tree in the OS directory dir into fs, merging it with
+// the existing contents. Imported files are written as by SetBytes, keeping
+// their modes and modification times, and empty directories are preserved.
+// Symbolic links are imported as links. Relative targets are kept as they
+// are, and absolute targets inside dir are made absolute within the MemFS;
+// a link to anything outside dir is an error.
+func (fs *MemFS) ImportFrom(dir string) error {
+	type entry struct {
+		name string
+		info os.FileInfo
+		b    []byte
+		link string
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	var entries []entry
+	err = filepath.WalkDir(root, func(p string, d iofs.DirEntry, err error) error {
+		if err != nil || p == root {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		e := entry{name: filepath.ToSlash(rel)}
+		if e.info, err = d.Info(); err != nil {
+			return err
+		}
+		switch {
+		case d.Type()&os.ModeSymlink != 0:
+			if e.link, err = os.Readlink(p); err != nil {
+				return err
+			}
+			if filepath.IsAbs(e.link) {
+				target, err := filepath.Rel(root, e.link)
+				if err != nil || !isWithin(root, filepath.Clean(e.link)) {
+					return fmt.Errorf("cannot import '%s'. Symbolic link points outside '%s'", e.name, dir)
+				}
+				e.link = "/" + filepath.ToSlash(target)
+			}
+		case !d.IsDir():
+			if e.b, err = os.ReadFile(p); err != nil {
+				return err
+			}
+			if e.b == nil {
+				e.b = make([]byte, 0) // A nil slice would make the node a directory
+			}
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	for _, e := range entries {
+		path := nameToPath(e.name)
+		switch {
+		case e.link != "":
+			node, err := fs.root.Lookup(false, path...)
+			if err != nil {
+				return err
+			}
+			if node == nil {
+				if node, err = fs.addFile(nil, path...); err != nil {
+					return err
+				}
+			}
+			if node == nil || node.B != nil || len(node.Children) > 0 {
+				return fmt.Errorf("cannot import symbolic link '%s'. Path exists", e.name)
+			}
+			node.LinkTarget = filepath.ToSlash(e.link)
+		case e.info.IsDir():
+			if fs.root.GetOrAdd(nil, path...) == nil {
+				return fmt.Errorf("cannot import directory '%s'. Path is a dangling symbolic link", e.name)
+			}
+		default:
+			if err := fs.setBytes(e.name, e.b, e.info.Mode().Perm()); err != nil {
+				return err
+			}
+			fs.root.Get(path...).ModTime = e.info.ModTime()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package simplefs
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestMemFSExportImport(t *testing.T) {
+	src := t.TempDir()
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, s := range map[string]string{"a": "a", "dir/b": "b", "dir/sub/c": "", "exec": "#!"} {
+		if err := WriteString(OsFS(src), name, s); err != nil {
+			t.Fatalf("WriteString() error: %v", err)
+		}
+		if err := os.Chtimes(path.Join(src, name), modTime, modTime); err != nil {
+			t.Fatalf("Chtimes() error: %v", err)
+		}
+	}
+	if err := os.Chmod(path.Join(src, "exec"), 0755); err != nil {
+		t.Fatalf("Chmod() error: %v", err)
+	}
+	if err := os.MkdirAll(path.Join(src, "empty"), 0777); err != nil {
+		t.Fatalf("MkdirAll() error: %v", err)
+	}
+	if err := os.Symlink("../a", path.Join(src, "dir", "link")); err != nil {
+		t.Fatalf("Symlink() error: %v", err)
+	}
+
+	fs := &MemFS{}
+	if err := fs.ImportFrom(src); err != nil {
+		t.Fatalf("ImportFrom() error: %v", err)
+	}
+	if b, err := ReadFile(fs, "dir/link"); err != nil || string(b) != "a" {
+		t.Fatalf("ReadFile(dir/link) returned %q, %v, want %q", b, err, "a")
+	}
+	if info, err := fs.Stat("dir/b"); err != nil || !info.ModTime().Equal(modTime) {
+		t.Fatalf("Stat(dir/b) returned %v, %v, want mod time %v", info, err, modTime)
+	}
+
+	dst := t.TempDir()
+	if err := fs.ExportTo(dst); err != nil {
+		t.Fatalf("ExportTo() error: %v", err)
+	}
+	if info, err := os.Stat(path.Join(dst, "empty")); err != nil || !info.IsDir() {
+		t.Fatalf("Stat(empty) after export returned %v, %v, want a directory", info, err)
+	}
+	if info, err := os.Stat(path.Join(dst, "exec")); err != nil || info.Mode().Perm() != 0755 {
+		t.Fatalf("Stat(exec) after export returned %v, %v, want mode 0755", info, err)
+	}
+	if info, err := os.Stat(path.Join(dst, "a")); err != nil || !info.ModTime().Equal(modTime) {
+		t.Fatalf("Stat(a) after export returned %v, %v, want mod time %v", info, err, modTime)
+	}
+
+	roundTrip := &MemFS{}
+	if err := roundTrip.ImportFrom(dst); err != nil {
+		t.Fatalf("ImportFrom() error: %v", err)
+	}
+	if !roundTrip.Equal(fs) {
+		t.Fatalf("Round trip produced\n%v\nwant\n%v", roundTrip.root, fs.root)
+	}
+}
@@ -0,0 +1,203 @@
+package simplefs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// OpenFile implements FileOpener. A file created with os.O_CREATE, or
+// truncated with os.O_TRUNC, is created or truncated immediately. Other
+// writes are made to a private copy of the file's contents and committed
+// when the file is closed, like writes to the writers returned by Create.
+func (fs *MemFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	fs.init()
+	fs.l.Lock()
+	defer fs.l.Unlock()
+
+	path, err := memPath("open", name)
+	if err != nil {
+		return nil, err
+	}
+	node, err := fs.root.Lookup(true, path...)
+	if err != nil {
+		return nil, err
+	}
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	switch {
+	case node == nil && flag&os.O_CREATE == 0:
+		return nil, notFound("open", name)
+	case node == nil:
+		if node, err = fs.addFile(make([]byte, 0), path...); err != nil {
+			return nil, &FSError{Op: "open", Path: name, Err: err}
+		}
+		if node == nil {
+			return nil, fmt.Errorf("cannot create '%s'. Path is a dangling symbolic link", name)
+		}
+		node.Mode = perm.Perm()
+		node.ModeSet = true
+		node.Dirty = true
+		fs.watchers.emit(node.Path(), OpCreate)
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &FSError{Op: "open", Path: name, Err: ErrExist}
+	case node.IsDirectory() && writable:
+		return nil, &FSError{Op: "open", Path: name, Err: ErrIsDir}
+	case node.IsDirectory():
+		return &memDir{fs: fs, name: name, info: node.FileInfo()}, nil
+	case flag&os.O_TRUNC != 0 && writable:
+		node.B = make([]byte, 0)
+		node.Dirty = true
+		node.ModTime = time.Now()
+		fs.watchers.emit(node.Path(), OpCreate)
+	}
+
+	return &memOpenFile{
+		fs:   fs,
+		name: name,
+		b:    append(make([]byte, 0, len(node.B)), node.B...),
+		flag: flag,
+	}, nil
+}
+
+// memOpenFile is a file opened with MemFS.OpenFile. It reads from and writes
+// to a private copy of the file's contents, which is committed on Close if it
+// has been written to.
+type memOpenFile struct {
+	fs       *MemFS
+	name     string
+	b        []byte
+	off      int64
+	flag     int
+	modified bool
+	closed   bool
+}
+
+func (f *memOpenFile) readable() bool {
+	return f.flag&os.O_WRONLY == 0
+}
+
+func (f *memOpenFile) writable() bool {
+	return f.flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+func (f *memOpenFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if !f.readable() {
+		return 0, fmt.Errorf("cannot read '%s'. File is not open for reading", f.name)
+	}
+	if f.off >= int64(len(f.b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.b[f.off:])
+	f.off += int64(n)
+	return n, nil
+}
+
+func (f *memOpenFile) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if !f.writable() {
+		return 0, fmt.Errorf("cannot write '%s'. File is not open for writing", f.name)
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.off = int64(len(f.b))
+	}
+	n := f.writeAt(p, f.off)
+	f.off += int64(n)
+	return n, nil
+}
+
+// WriteAt writes p at offset off without moving the file offset. If off is
+// past the end of the file, the gap is filled with zeros. Like os.File, it
+// returns an error for files opened with os.O_APPEND.
+func (f *memOpenFile) WriteAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if !f.writable() {
+		return 0, fmt.Errorf("cannot write '%s'. File is not open for writing", f.name)
+	}
+	if f.flag&os.O_APPEND != 0 {
+		return 0, fmt.Errorf("cannot write '%s' at offset. File is open for appending", f.name)
+	}
+	if off < 0 {
+		return 0, fmt.Errorf("cannot write '%s' at negative offset", f.name)
+	}
+	return f.writeAt(p, off), nil
+}
+
+func (f *memOpenFile) writeAt(p []byte, off int64) int {
+	if end := off + int64(len(p)); end > int64(len(f.b)) {
+		f.b = append(f.b, make([]byte, end-int64(len(f.b)))...)
+	}
+	n := copy(f.b[off:], p)
+	f.modified = true
+	return n
+}
+
+func (f *memOpenFile) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += int64(len(f.b))
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("cannot seek '%s' to negative offset", f.name)
+	}
+	f.off = offset
+	return offset, nil
+}
+
+func (f *memOpenFile) Stat() (os.FileInfo, error) {
+	f.fs.l.RLock()
+	defer f.fs.l.RUnlock()
+	info := &fileInfo{name: f.name, size: int64(len(f.b)), mode: defaultFileMode}
+	if node := f.fs.root.Get(nameToPath(f.name)...); node != nil {
+		info = node.FileInfo()
+		info.size = int64(len(f.b))
+	}
+	return info, nil
+}
+
+func (f *memOpenFile) Close() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	if !f.modified {
+		return nil
+	}
+	f.fs.l.Lock()
+	defer f.fs.l.Unlock()
+	node, err := f.fs.addFile(f.b, nameToPath(f.name)...)
+	if err != nil {
+		return &FSError{Op: "close", Path: f.name, Err: err}
+	}
+	if node == nil {
+		return fmt.Errorf("cannot create '%s'. Path is a dangling symbolic link", f.name)
+	}
+	if node.IsDirectory() {
+		// The file was replaced by a directory while it was open.
+		return &FSError{Op: "close", Path: f.name, Err: ErrIsDir}
+	}
+	node.B = f.b
+	node.Dirty = true
+	node.ModTime = time.Now()
+	f.fs.watchers.emit(node.Path(), OpWrite)
+	return nil
+}
+
+func (f *memOpenFile) ReadDir(n int) ([]DirEntry, error) {
+	return nil, &FSError{Op: "readdir", Path: f.name, Err: ErrNotDir}
+}
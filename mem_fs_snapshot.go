@@ -0,0 +1,243 @@
+package simplefs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// ErrBadFormat is returned by ReadMemFS for input that is not a valid
+// snapshot, including truncated input.
+var ErrBadFormat = fmt.Errorf("invalid MemFS snapshot")
+
+// ErrUnsupportedVersion is returned by ReadMemFS for a snapshot written by a
+// newer version of the format.
+var ErrUnsupportedVersion = fmt.Errorf("unsupported MemFS snapshot version")
+
+// snapshotMagic starts every snapshot, followed by a version byte.
+const snapshotMagic = "SFSM"
+const snapshotVersion = 1
+
+// Record kinds of a snapshot. The end of a snapshot is marked by
+// snapshotEnd.
+const (
+	snapshotEnd  = 0
+	snapshotDir  = 'd'
+	snapshotFile = 'f'
+	snapshotLink = 'l'
+)
+
+// WriteTo implements io.WriterTo by writing a snapshot of fs that ReadMemFS
+// can read back.
+//
+// A snapshot starts with the magic "SFSM" and a version byte, followed by a
+// record for each file, directory and symbolic link, and a zero byte. A
+// record is a kind byte ('f', 'd' or 'l'), the path, a metadata block and,
+// for files and links, the contents or link target. Strings and blocks are
+// prefixed by their length as a uvarint. The metadata block holds the mode
+// as a uvarint, the modification time as a varint of Unix nanoseconds, or 0
+// if it is unknown, and a uvarint that is 1 if the mode was set explicitly,
+// so that a zero mode is not the default. Fields added in the future are
+// appended to the block, and readers skip the ones they do not know.
+func (fs *MemFS) WriteTo(w io.Writer) (int64, error) {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	_, _ = bw.WriteString(snapshotMagic)
+	_ = bw.WriteByte(snapshotVersion)
+	var buf [binary.MaxVarintLen64]byte
+	writeBytes := func(b []byte) {
+		_, _ = bw.Write(buf[:binary.PutUvarint(buf[:], uint64(len(b)))])
+		_, _ = bw.Write(b)
+	}
+	fs.root.DFS(func(node *dirNode) {
+		if node == fs.root {
+			return
+		}
+		var contents []byte
+		switch {
+		case node.IsLink():
+			_ = bw.WriteByte(snapshotLink)
+			contents = []byte(node.LinkTarget)
+		case node.IsDirectory():
+			_ = bw.WriteByte(snapshotDir)
+		default:
+			_ = bw.WriteByte(snapshotFile)
+			contents = node.B
+		}
+		writeBytes([]byte(node.Path()))
+		var meta []byte
+		meta = binary.AppendUvarint(meta, uint64(node.Mode))
+		var modTime int64
+		if !node.ModTime.IsZero() {
+			modTime = node.ModTime.UnixNano()
+		}
+		meta = binary.AppendVarint(meta, modTime)
+		var modeSet uint64
+		if node.ModeSet {
+			modeSet = 1
+		}
+		meta = binary.AppendUvarint(meta, modeSet)
+		writeBytes(meta)
+		if !node.IsDirectory() {
+			writeBytes(contents)
+		}
+	})
+	_ = bw.WriteByte(snapshotEnd)
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ReadMemFS reads a snapshot written by MemFS.WriteTo. It returns an error
+// wrapping ErrBadFormat if r does not hold a complete snapshot, and one
+// wrapping ErrUnsupportedVersion if the snapshot was written in a newer
+// format. The files of the returned MemFS are not dirty.
+func ReadMemFS(r io.Reader) (*MemFS, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, badSnapshot(err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, fmt.Errorf("%w: wrong magic %q", ErrBadFormat, header[:len(snapshotMagic)])
+	}
+	if version := header[len(snapshotMagic)]; version > snapshotVersion {
+		return nil, fmt.Errorf("%w: version %d, want at most %d", ErrUnsupportedVersion, version, snapshotVersion)
+	} else if version == 0 {
+		return nil, fmt.Errorf("%w: version 0", ErrBadFormat)
+	}
+
+	fs := &MemFS{root: &dirNode{}}
+	for {
+		kind, err := br.ReadByte()
+		if err != nil {
+			return nil, badSnapshot(err)
+		}
+		if kind == snapshotEnd {
+			break
+		}
+		if kind != snapshotDir && kind != snapshotFile && kind != snapshotLink {
+			return nil, fmt.Errorf("%w: unknown record kind %q", ErrBadFormat, kind)
+		}
+		name, err := readSnapshotBytes(br)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := readSnapshotBytes(br)
+		if err != nil {
+			return nil, err
+		}
+		var contents []byte
+		if kind != snapshotDir {
+			if contents, err = readSnapshotBytes(br); err != nil {
+				return nil, err
+			}
+		}
+
+		path := nameToPath(string(name))
+		if len(path) == 0 || path[0] == "." || path[0] == ".." || fs.root.Get(path...) != nil {
+			return nil, fmt.Errorf("%w: invalid or duplicate path %q", ErrBadFormat, name)
+		}
+		var node *dirNode
+		switch kind {
+		case snapshotDir:
+			node = fs.root.AddDescendant(nil, path...)
+		case snapshotFile:
+			node = fs.root.AddDescendant(contents, path...)
+		case snapshotLink:
+			if len(contents) == 0 {
+				return nil, fmt.Errorf("%w: empty link target for %q", ErrBadFormat, name)
+			}
+			if node = fs.root.AddDescendant(nil, path...); node != nil {
+				node.LinkTarget = string(contents)
+			}
+		}
+		if node == nil {
+			return nil, fmt.Errorf("%w: cannot create %q", ErrBadFormat, name)
+		}
+		if err := readSnapshotMeta(node, meta); err != nil {
+			return nil, fmt.Errorf("%w: metadata of %q: %v", ErrBadFormat, name, err)
+		}
+	}
+	fs.numFiles = countFiles(fs.root)
+	return fs, nil
+}
+
+// readSnapshotMeta sets the metadata of node from a metadata block. Fields
+// after the ones known to this version are ignored.
+func readSnapshotMeta(node *dirNode, meta []byte) error {
+	r := bytes.NewReader(meta)
+	mode, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	modTime, err := binary.ReadVarint(r)
+	if err != nil {
+		return err
+	}
+	// Snapshots written before the flag was added do not have it.
+	modeSet, err := binary.ReadUvarint(r)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if !node.IsDirectory() && !node.IsLink() {
+		node.Mode = os.FileMode(mode).Perm()
+		node.ModeSet = modeSet == 1
+	}
+	if modTime != 0 {
+		node.ModTime = time.Unix(0, modTime)
+	}
+	return nil
+}
+
+// readSnapshotBytes reads a uvarint length followed by that many bytes. It
+// does not trust the length to allocate, so that a corrupt length fails with
+// ErrBadFormat rather than exhausting memory.
+func readSnapshotBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, badSnapshot(err)
+	}
+	if n > math.MaxInt64 {
+		return nil, fmt.Errorf("%w: length %d out of range", ErrBadFormat, n)
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, badSnapshot(err)
+	}
+	b := buf.Bytes()
+	if b == nil {
+		b = make([]byte, 0) // A nil slice would make the node a directory
+	}
+	return b, nil
+}
+
+// badSnapshot returns an error wrapping ErrBadFormat for an error reading a
+// snapshot. Running out of input is reported as truncation.
+func badSnapshot(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated", ErrBadFormat)
+	}
+	return fmt.Errorf("%w: %v", ErrBadFormat, err)
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
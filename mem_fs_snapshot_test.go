@@ -0,0 +1,91 @@
+package simplefs
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemFSSnapshot(t *testing.T) {
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	fs := NewMemFSFromStrings(map[string]string{"a": "alpha", "dir/b": "", "dir/sub/c": "gamma"})
+	w, _ := fs.CreateMode("exec", 0755)
+	_ = w.Close()
+	_ = fs.MkdirAll("empty/dir")
+	_ = fs.Symlink("../a", "dir/link")
+	fs.root.Get("a").ModTime = modTime
+
+	var buf bytes.Buffer
+	n, err := fs.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() error: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("WriteTo() returned %d, but wrote %d bytes", n, buf.Len())
+	}
+	snapshot := buf.Bytes()
+
+	got, err := ReadMemFS(bytes.NewReader(snapshot))
+	if err != nil {
+		t.Fatalf("ReadMemFS() error: %v", err)
+	}
+	for name, want := range map[string]string{"a": "alpha", "dir/b": "", "dir/sub/c": "gamma", "dir/link": "alpha"} {
+		if b, err := got.Bytes(name); err != nil || string(b) != want {
+			t.Fatalf("Bytes(%s) returned %q, %v, want %q", name, b, err, want)
+		}
+	}
+	if dirs, _ := got.ListDirs("."); !reflect.DeepEqual(dirs, []string{"dir", "dir/sub", "empty", "empty/dir"}) {
+		t.Fatalf("ListDirs() returned %v", dirs)
+	}
+	if target, _ := got.Readlink("dir/link"); target != "../a" {
+		t.Fatalf("Readlink() returned %q, want %q", target, "../a")
+	}
+	if info, _ := got.Stat("exec"); info.Mode() != 0755 {
+		t.Fatalf("Stat(exec).Mode() returned %v, want %v", info.Mode(), os.FileMode(0755))
+	}
+	if info, _ := got.Stat("a"); !info.ModTime().Equal(modTime) {
+		t.Fatalf("Stat(a).ModTime() returned %v, want %v", info.ModTime(), modTime)
+	}
+	if dirty := got.DirtyFiles(); len(dirty) != 0 {
+		t.Fatalf("DirtyFiles() returned %v, want none", dirty)
+	}
+
+	t.Run("Truncated", func(t *testing.T) {
+		for i := 0; i < len(snapshot); i++ {
+			if _, err := ReadMemFS(bytes.NewReader(snapshot[:i])); !errors.Is(err, ErrBadFormat) {
+				t.Fatalf("ReadMemFS() of %d of %d bytes returned %v, want ErrBadFormat", i, len(snapshot), err)
+			}
+		}
+	})
+
+	t.Run("Wrong magic", func(t *testing.T) {
+		b := append([]byte("ZIP!"), snapshot[4:]...)
+		if _, err := ReadMemFS(bytes.NewReader(b)); !errors.Is(err, ErrBadFormat) {
+			t.Fatalf("ReadMemFS() returned %v, want ErrBadFormat", err)
+		}
+	})
+
+	t.Run("Future version", func(t *testing.T) {
+		b := append([]byte{}, snapshot...)
+		b[len(snapshotMagic)] = snapshotVersion + 1
+		if _, err := ReadMemFS(bytes.NewReader(b)); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Fatalf("ReadMemFS() returned %v, want ErrUnsupportedVersion", err)
+		}
+	})
+
+	t.Run("Unknown metadata", func(t *testing.T) {
+		// A record whose metadata block has a field this version doesn't
+		// know about is still read.
+		b := []byte(snapshotMagic + "\x01" + "f\x01x" + "\x03\x00\x00\x07" + "\x02hi" + "\x00")
+		fs, err := ReadMemFS(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("ReadMemFS() error: %v", err)
+		}
+		if b, err := fs.Bytes("x"); err != nil || string(b) != "hi" {
+			t.Fatalf("Bytes() returned %q, %v, want %q", b, err, "hi")
+		}
+	})
+}
@@ -1,6 +1,14 @@
 package simplefs
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -9,3 +17,1077 @@ func TestInMemoryFileSystem(t *testing.T) {
 		t.Fatal(msg)
 	}
 }
+
+func TestMemFSDirtyFiles(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetString("a", "a")
+	fs.SetString("dir/b", "b")
+	fs.SetString("dir/c", "c")
+
+	assertDirty := func(want ...string) {
+		t.Helper()
+		got := fs.DirtyFiles()
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("DirtyFiles() returned %v, want %v", got, want)
+		}
+	}
+
+	assertDirty("a", "dir/b", "dir/c")
+	assertDirty()
+
+	w, _ := fs.Append("dir/b")
+	_, _ = w.Write([]byte("b"))
+	_ = w.Close()
+	fs.SetString("a", "A")
+	assertDirty("a", "dir/b")
+
+	fs.SetString("a", "a")
+	fs.SetString("dir/c", "C")
+	fs.MarkClean("dir/c", "missing")
+	assertDirty("a")
+}
+
+func TestMemFSRemovedFiles(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{
+		"a": "a", "b": "b", "c": "c", "dir/d": "d", "dir/e": "e", "src/f": "f", "dst/f": "f", "gone/g": "g",
+	})
+	assertRemoved := func(want ...string) {
+		t.Helper()
+		if got := fs.RemovedFiles(); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("RemovedFiles() returned %v, want %v", got, want)
+		}
+	}
+
+	assertRemoved()
+	if err := fs.Remove("a"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	if err := fs.Rename("b", "c"); err != nil {
+		t.Fatalf("Rename() error: %v", err)
+	}
+	if err := fs.Rename("dir", "moved"); err != nil {
+		t.Fatalf("Rename() of a directory error: %v", err)
+	}
+	if err := fs.MoveMerge("src", "dst"); err != nil {
+		t.Fatalf("MoveMerge() error: %v", err)
+	}
+	if err := fs.RemoveAll("gone"); err != nil {
+		t.Fatalf("RemoveAll() error: %v", err)
+	}
+	// "c" was replaced, but exists again and is reported by DirtyFiles.
+	assertRemoved("a", "b", "dir/d", "dir/e", "gone/g", "src/f")
+	assertRemoved()
+
+	fs.SetString("x", "x")
+	if err := fs.Remove("x"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	fs.SetString("x", "again")
+	assertRemoved()
+
+	fs.Reset()
+	assertRemoved("c", "dst/f", "moved/d", "moved/e", "x")
+}
+
+func TestMemFSListFiles(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{"a": "a", "dir/b": "b"})
+	for dir, want := range map[string]string{".": ",/a,/dir,/dir/b", "dir": "/dir,/dir/b"} {
+		got, err := fs.ListFiles(dir)
+		if err != nil || strings.Join(got, ",") != want {
+			t.Fatalf("ListFiles(%s) returned %q, %v, want %q", dir, got, err, want)
+		}
+	}
+}
+
+func TestMemFSCreateMode(t *testing.T) {
+	fs := &MemFS{}
+	if err := WriteFile(fs, "plain", nil); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	w, err := CreateMode(fs, "script.sh", 0755)
+	if err != nil {
+		t.Fatalf("CreateMode() error: %v", err)
+	}
+	_ = w.Close()
+	// An explicit zero mode is set, like it is by OsFS.
+	if w, err = CreateMode(fs, "private", 0); err != nil {
+		t.Fatalf("CreateMode() error: %v", err)
+	}
+	_ = w.Close()
+	// A plain Create keeps the mode of an existing file
+	for _, name := range []string{"script.sh", "private"} {
+		if err := WriteString(fs, name, "#!/bin/sh"); err != nil {
+			t.Fatalf("WriteString() error: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := fs.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error: %v", err)
+	}
+	restored, err := ReadMemFS(&buf)
+	if err != nil {
+		t.Fatalf("ReadMemFS() error: %v", err)
+	}
+	for _, fs := range []*MemFS{fs, restored} {
+		for name, want := range map[string]os.FileMode{"plain": 0666, "script.sh": 0755, "private": 0} {
+			info, err := fs.Stat(name)
+			if err != nil {
+				t.Fatalf("Stat(%s) error: %v", name, err)
+			}
+			if info.Mode() != want {
+				t.Fatalf("Stat(%s).Mode() returned %v, want %v", name, info.Mode(), want)
+			}
+		}
+	}
+}
+
+func TestMemFSSymlink(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetString("file", "contents")
+	fs.SetString("dir/a", "a")
+	links := map[string]string{
+		"link-file":   "file",
+		"link-dir":    "dir",
+		"dir/up":      "../file",
+		"abs":         "/dir/a",
+		"chain":       "link-dir",
+		"loop1":       "loop2",
+		"loop2":       "loop1",
+		"self/nested": "../self/nested",
+	}
+	for linkName, target := range links {
+		if err := fs.Symlink(target, linkName); err != nil {
+			t.Fatalf("Symlink(%s, %s) error: %v", target, linkName, err)
+		}
+	}
+	if err := fs.Symlink("file", "link-file"); err == nil {
+		t.Fatalf("Symlink() on existing path returned nil error")
+	}
+
+	for name, want := range map[string]string{
+		"link-file":        "contents",
+		"dir/up":           "contents",
+		"abs":              "a",
+		"link-dir/a":       "a",
+		"chain/a":          "a",
+		"chain/up":         "contents",
+		"link-dir/../file": "contents",
+	} {
+		b, err := ReadFile(fs, name)
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error: %v", name, err)
+		}
+		if string(b) != want {
+			t.Fatalf("ReadFile(%s) returned %q, want %q", name, b, want)
+		}
+	}
+
+	entries, err := fs.ReadDir("link-dir")
+	if err != nil {
+		t.Fatalf("ReadDir(link-dir) error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "a" || entries[1].Name() != "up" {
+		t.Fatalf("ReadDir(link-dir) returned %v", entries)
+	}
+
+	// Writing through a link to a directory creates the file in the target
+	if err := WriteString(fs, "link-dir/b", "b"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if b, err := ReadFile(fs, "dir/b"); err != nil || string(b) != "b" {
+		t.Fatalf("ReadFile(dir/b) returned %q, %v", b, err)
+	}
+
+	if target, err := fs.Readlink("dir/up"); err != nil || target != "../file" {
+		t.Fatalf("Readlink(dir/up) returned %q, %v", target, err)
+	}
+	if _, err := fs.Readlink("file"); err == nil {
+		t.Fatalf("Readlink() on regular file returned nil error")
+	}
+
+	for _, name := range []string{"loop1", "loop2/x", "self/nested"} {
+		if _, err := fs.Open(name); !errors.Is(err, ErrTooManyLinks) {
+			t.Fatalf("Open(%s) returned %v, want ErrTooManyLinks", name, err)
+		}
+	}
+}
+
+func TestMemFSRemove(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetString("a", "a")
+	fs.SetString("dir/b", "b")
+	fs.SetString("dir/sub/c", "c")
+
+	if err := fs.Remove("a"); err != nil {
+		t.Fatalf("Remove(a) error: %v", err)
+	}
+	if _, err := fs.Open("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open(a) after Remove returned %v, want ErrNotFound", err)
+	}
+	if err := fs.Remove("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Remove(a) twice returned %v, want ErrNotFound", err)
+	}
+	if err := fs.Remove("dir"); err == nil {
+		t.Fatalf("Remove() on non-empty directory returned nil error")
+	}
+	if err := fs.RemoveAll("dir/sub"); err != nil {
+		t.Fatalf("RemoveAll(dir/sub) error: %v", err)
+	}
+	if err := fs.RemoveAll("dir/sub"); err != nil {
+		t.Fatalf("RemoveAll() on missing path returned %v", err)
+	}
+	entries, err := fs.ReadDir("dir")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "b" {
+		t.Fatalf("ReadDir(dir) returned %v, %v", entries, err)
+	}
+	if err := fs.RemoveAll("."); err != nil {
+		t.Fatalf("RemoveAll(.) error: %v", err)
+	}
+	if entries, err := fs.ReadDir("."); err != nil || len(entries) != 0 {
+		t.Fatalf("ReadDir(.) after RemoveAll returned %v, %v", entries, err)
+	}
+}
+
+func TestNewMemFS(t *testing.T) {
+	fs := NewMemFS(map[string][]byte{
+		"b":       []byte("b"),
+		"dir/z":   []byte("z"),
+		"dir/a":   []byte("a"),
+		"a":       nil,
+		"dir/s/x": []byte("x"),
+	})
+	want := NewMemFSFromStrings(map[string]string{
+		"dir/s/x": "x",
+		"a":       "",
+		"dir/a":   "a",
+		"b":       "b",
+		"dir/z":   "z",
+	})
+	if !fs.Equal(want) {
+		t.Fatalf("NewMemFS() and NewMemFSFromStrings() returned different trees:\n%v\n%v", fs.root, want.root)
+	}
+	entries, err := fs.ReadDir("dir")
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if !compareDirEntries(entries, []DirEntry{&dirEntry{name: "a"}, &dirEntry{name: "s", isDir: true}, &dirEntry{name: "z"}}) {
+		t.Fatalf("ReadDir() returned %v", entries)
+	}
+	if b, err := ReadFile(fs, "a"); err != nil || len(b) != 0 {
+		t.Fatalf("ReadFile(a) returned %q, %v, want empty file", b, err)
+	}
+}
+
+func TestMemFSReset(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{"a": "a", "dir/b": "b"})
+	fs.Reset()
+	entries, err := fs.ReadDir(".")
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("ReadDir() after Reset() returned %v, want no entries", entries)
+	}
+	if _, err := fs.Open("dir/b"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open() after Reset() returned %v, want %v", err, ErrNotFound)
+	}
+	if err := WriteString(fs, "c", "c"); err != nil {
+		t.Fatalf("WriteString() after Reset() error: %v", err)
+	}
+}
+
+func TestListDirs(t *testing.T) {
+	files := map[string]string{"a": "", "dir/b": "", "dir/sub/c": "", "dir/sub/deep/d": "", "other/e": ""}
+	mem := NewMemFSFromStrings(files)
+	osFS := OsFS(t.TempDir())
+	for name, s := range files {
+		if err := WriteString(osFS, name, s); err != nil {
+			t.Fatalf("WriteString() error: %v", err)
+		}
+	}
+	type dirLister interface {
+		ListDirs(dir string) ([]string, error)
+	}
+	for name, fs := range map[string]dirLister{"MemFS": mem, "OsFS": osFS.(dirLister)} {
+		for dir, want := range map[string]string{
+			".":       "dir,dir/sub,dir/sub/deep,other",
+			"dir":     "dir/sub,dir/sub/deep",
+			"dir/sub": "dir/sub/deep",
+			"other":   "",
+		} {
+			got, err := fs.ListDirs(dir)
+			if err != nil {
+				t.Fatalf("%s: ListDirs(%s) error: %v", name, dir, err)
+			}
+			if strings.Join(got, ",") != want {
+				t.Fatalf("%s: ListDirs(%s) returned %v, want %s", name, dir, got, want)
+			}
+		}
+		for _, dir := range []string{"a", "missing"} {
+			if _, err := fs.ListDirs(dir); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("%s: ListDirs(%s) returned %v, want %v", name, dir, err, ErrNotFound)
+			}
+		}
+	}
+}
+
+func TestMemFSEachFile(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{"a": "", "dir/b": "", "dir/sub/c": "", "dir/sub/d": "", "e": ""})
+	_ = fs.Symlink("a", "link")
+
+	var got []string
+	collect := func(path string) error {
+		got = append(got, path)
+		return nil
+	}
+	if err := fs.EachFile(".", collect); err != nil {
+		t.Fatalf("EachFile() error: %v", err)
+	}
+	if strings.Join(got, ",") != "a,dir/b,dir/sub/c,dir/sub/d,e" {
+		t.Fatalf("EachFile() visited %v", got)
+	}
+
+	got = nil
+	stop := errors.New("stop")
+	err := fs.EachFile("dir", func(path string) error {
+		got = append(got, path)
+		if path == "dir/sub/c" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || strings.Join(got, ",") != "dir/b,dir/sub/c" {
+		t.Fatalf("EachFile(dir) returned %v after visiting %v, want %v after dir/b,dir/sub/c", err, got, stop)
+	}
+
+	for _, dir := range []string{"a", "missing"} {
+		if err := fs.EachFile(dir, collect); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("EachFile(%s) returned %v, want %v", dir, err, ErrNotFound)
+		}
+	}
+}
+
+func TestMemFSMessyPaths(t *testing.T) {
+	fs := &MemFS{}
+	for _, name := range []string{"/dir/file", "dir/file/", "dir//file", "./dir/./file", "dir/sub/../file"} {
+		if err := WriteString(fs, name, name); err != nil {
+			t.Fatalf("WriteString(%s) error: %v", name, err)
+		}
+		if b, err := ReadFile(fs, "dir/file"); err != nil || string(b) != name {
+			t.Fatalf("ReadFile(dir/file) after writing %s returned %q, %v", name, b, err)
+		}
+	}
+	want := NewMemFSFromStrings(map[string]string{"dir/file": "dir/sub/../file"})
+	if !fs.Equal(want) {
+		t.Fatalf("Writing messy paths produced tree:\n%v\nwant:\n%v", fs.root, want.root)
+	}
+	for _, name := range []string{"", ".", "/", "//"} {
+		entries, err := fs.ReadDir(name)
+		if err != nil || len(entries) != 1 || entries[0].Name() != "dir" {
+			t.Fatalf("ReadDir(%q) returned %v, %v, want [dir]", name, entries, err)
+		}
+	}
+}
+
+func TestMemFSReadDirRoot(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{"b": "", "a/c": ""})
+	entries, err := fs.ReadDir("")
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if !compareDirEntries(entries, []DirEntry{&dirEntry{name: "a", isDir: true}, &dirEntry{name: "b"}}) {
+		t.Fatalf("ReadDir() returned %v", entries)
+	}
+	if got := fs.root.Get(); got != fs.root {
+		t.Fatalf("Get() with an empty path returned %v, want the root", got)
+	}
+}
+
+func TestMemFSBytes(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{"dir/file": "contents"})
+	b, err := fs.Bytes("dir/file")
+	if err != nil || string(b) != "contents" {
+		t.Fatalf("Bytes() returned %q, %v, want %q", b, err, "contents")
+	}
+	b[0] = 'X'
+	if b, _ := fs.Bytes("dir/file"); string(b) != "contents" {
+		t.Fatalf("Modifying the result of Bytes() changed the file to %q", b)
+	}
+	if _, err := fs.Bytes("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Bytes(missing) returned %v, want %v", err, ErrNotFound)
+	}
+	if _, err := fs.Bytes("dir"); err == nil {
+		t.Fatalf("Bytes(dir) returned nil error")
+	}
+}
+
+func TestMemFSSizeAndCounts(t *testing.T) {
+	fs := &MemFS{}
+	if fs.Size() != 0 || fs.NumFiles() != 0 || fs.NumDirs() != 0 {
+		t.Fatalf("Empty MemFS has Size() %d, NumFiles() %d, NumDirs() %d", fs.Size(), fs.NumFiles(), fs.NumDirs())
+	}
+	fs = NewMemFSFromStrings(map[string]string{"a": "123", "dir/b": "45", "dir/sub/c": "", "other/d": "6789"})
+	if got := fs.Size(); got != 9 {
+		t.Fatalf("Size() returned %d, want 9", got)
+	}
+	if got := fs.NumFiles(); got != 4 {
+		t.Fatalf("NumFiles() returned %d, want 4", got)
+	}
+	if got := fs.NumDirs(); got != 3 {
+		t.Fatalf("NumDirs() returned %d, want 3", got)
+	}
+}
+
+func TestMemFSOpenSeekable(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{"file": "0123456789"})
+	f, err := fs.Open("file")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	b := make([]byte, 3)
+	if _, err := f.(io.ReaderAt).ReadAt(b, 5); err != nil || string(b) != "567" {
+		t.Fatalf("ReadAt() returned %q, %v, want %q", b, err, "567")
+	}
+	if _, err := f.(io.Seeker).Seek(-2, io.SeekEnd); err != nil {
+		t.Fatalf("Seek() error: %v", err)
+	}
+	if rest, err := io.ReadAll(f); err != nil || string(rest) != "89" {
+		t.Fatalf("ReadAll() after Seek() returned %q, %v, want %q", rest, err, "89")
+	}
+}
+
+// TestMemFSConcurrentOpenAppend is meant to be run with -race.
+func TestMemFSConcurrentOpenAppend(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetString("file", "a")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w, _ := fs.Append("file")
+				_, _ = w.Write([]byte("a"))
+				_ = w.Close()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				b, err := ReadFile(fs, "file")
+				if err != nil {
+					t.Errorf("ReadFile() error: %v", err)
+					return
+				}
+				if strings.Trim(string(b), "a") != "" {
+					t.Errorf("ReadFile() returned %q", b)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if b, _ := ReadFile(fs, "file"); len(b) != 201 {
+		t.Fatalf("File has %d bytes after appends, want 201", len(b))
+	}
+}
+
+func TestMemFSReadAt(t *testing.T) {
+	const pageSize, numPages = 64, 32
+	data := make([]byte, pageSize*numPages)
+	for i := range data {
+		data[i] = byte(i / pageSize)
+	}
+	fs := NewMemFS(map[string][]byte{"db": data})
+	f, err := fs.Open("db")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	r, ok := f.(io.ReaderAt)
+	if !ok {
+		t.Fatalf("%T does not implement io.ReaderAt", f)
+	}
+
+	var wg sync.WaitGroup
+	for page := 0; page < numPages; page++ {
+		wg.Add(1)
+		go func(page int) {
+			defer wg.Done()
+			b := make([]byte, pageSize)
+			if n, err := r.ReadAt(b, int64(page*pageSize)); n != pageSize || err != nil {
+				t.Errorf("ReadAt(page %d) returned %d, %v", page, n, err)
+				return
+			}
+			if !bytes.Equal(b, bytes.Repeat([]byte{byte(page)}, pageSize)) {
+				t.Errorf("ReadAt(page %d) returned the wrong page", page)
+			}
+		}(page)
+	}
+	wg.Wait()
+
+	b := make([]byte, pageSize)
+	if n, err := r.ReadAt(b, int64(len(data)-10)); n != 10 || err != io.EOF {
+		t.Fatalf("ReadAt() across the end returned %d, %v, want 10, EOF", n, err)
+	}
+	if n, err := r.ReadAt(b, int64(len(data)+10)); n != 0 || err != io.EOF {
+		t.Fatalf("ReadAt() past the end returned %d, %v, want 0, EOF", n, err)
+	}
+}
+
+func TestMemFSReplace(t *testing.T) {
+	fs := &MemFS{}
+	versions := [][]byte{bytes.Repeat([]byte("a"), 4096), bytes.Repeat([]byte("b"), 8192)}
+	if err := fs.Replace("config", versions[0]); err != nil {
+		t.Fatalf("Replace() error: %v", err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				b, err := ReadFile(fs, "config")
+				if err != nil {
+					t.Errorf("ReadFile() error: %v", err)
+					return
+				}
+				if !bytes.Equal(b, versions[0]) && !bytes.Equal(b, versions[1]) {
+					t.Errorf("ReadFile() returned a torn version of %d bytes", len(b))
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		if err := fs.Replace("config", versions[i%2]); err != nil {
+			t.Fatalf("Replace() error: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	b := []byte("mine")
+	_ = fs.Replace("config", b)
+	b[0] = 'X'
+	if got, _ := fs.Bytes("config"); string(got) != "mine" {
+		t.Fatalf("Replace() did not copy its argument: file contains %q", got)
+	}
+	fs.SetString("dir/file", "")
+	if err := fs.Replace("dir", []byte("x")); err == nil {
+		t.Fatalf("Replace() on a directory returned nil error")
+	}
+}
+
+func TestMemFSCompact(t *testing.T) {
+	fs := &MemFS{}
+	for i := 0; i < 1000; i++ {
+		w, _ := fs.Append("log")
+		_, _ = w.Write([]byte("entry\n"))
+		_ = w.Close()
+	}
+	fs.SetString("other", "x")
+	before := fs.root.Get("log").B
+	if cap(before) == len(before) {
+		t.Fatalf("Appends left no spare capacity to compact")
+	}
+
+	fs.Compact()
+	after := fs.root.Get("log").B
+	if cap(after) != len(after) {
+		t.Fatalf("Compact() left capacity %d for %d bytes", cap(after), len(after))
+	}
+	if !bytes.Equal(after, before) {
+		t.Fatalf("Compact() changed the file contents")
+	}
+	if b, _ := fs.Bytes("other"); string(b) != "x" {
+		t.Fatalf("Bytes() after Compact() returned %q", b)
+	}
+}
+
+func TestMemFSFork(t *testing.T) {
+	appendString := func(fs *MemFS, name, s string) {
+		w, _ := fs.Append(name)
+		_, _ = w.Write([]byte(s))
+		_ = w.Close()
+	}
+	fs := NewMemFSFromStrings(map[string]string{"dir/a": "a", "dir/b": "b", "big": strings.Repeat("x", 1<<10)})
+	for i := 0; i < 10; i++ {
+		appendString(fs, "log", "entry\n")
+	}
+	fork := fs.Fork()
+	if !fork.Equal(fs) {
+		t.Fatalf("Fork() returned a different tree")
+	}
+	if &fork.root.Get("big").B[0] != &fs.root.Get("big").B[0] {
+		t.Fatalf("Fork() copied the contents of a file")
+	}
+
+	// Appending to both may not write to a shared array.
+	appendString(fork, "log", "fork\n")
+	appendString(fs, "log", "parent\n")
+	fork.SetString("dir/a", "fork")
+	fs.SetString("dir/b", "parent")
+	_ = fork.Remove("big")
+	fs.SetString("new", "parent")
+
+	for name, want := range map[string]string{
+		"log":   strings.Repeat("entry\n", 10) + "fork\n",
+		"dir/a": "fork",
+		"dir/b": "b",
+	} {
+		if b, err := fork.Bytes(name); err != nil || string(b) != want {
+			t.Fatalf("Fork: Bytes(%s) returned %q, %v, want %q", name, b, err, want)
+		}
+	}
+	for name, want := range map[string]string{
+		"log":   strings.Repeat("entry\n", 10) + "parent\n",
+		"dir/a": "a",
+		"dir/b": "parent",
+		"big":   strings.Repeat("x", 1<<10),
+	} {
+		if b, err := fs.Bytes(name); err != nil || string(b) != want {
+			t.Fatalf("Parent: Bytes(%s) returned %q, %v, want %q", name, b, err, want)
+		}
+	}
+	for _, name := range []string{"big", "new"} {
+		if _, err := fork.Bytes(name); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Fork: Bytes(%s) returned %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestMemFSOpenSnapshot(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetBytes("file", bytes.Repeat([]byte("a"), 1<<20))
+
+	// Opening a file shares its bytes rather than copying them.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < 100; i++ {
+		f, _ := fs.Open("file")
+		_ = f.Close()
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Fatalf("Opening the file 100 times allocated %d bytes", allocated)
+	}
+
+	// Readers see the file as it was when opened, while a writer replaces
+	// and appends to it concurrently.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			fs.SetBytes("file", bytes.Repeat([]byte{byte('b' + i%2)}, 1<<10))
+			w, _ := fs.Append("file")
+			_, _ = w.Write(bytes.Repeat([]byte{byte('b' + i%2)}, 1<<10))
+			_ = w.Close()
+		}
+	}()
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				f, err := fs.Open("file")
+				if err != nil {
+					t.Errorf("Open() error: %v", err)
+					return
+				}
+				b, err := io.ReadAll(f)
+				if err != nil {
+					t.Errorf("Read() error: %v", err)
+					return
+				}
+				if len(b) == 0 || len(bytes.Trim(b, string(b[:1]))) != 0 {
+					t.Errorf("Read() returned a mix of writes")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	f, _ := fs.Open("file")
+	fs.SetString("file", "replaced")
+	w, _ := fs.Append("file")
+	_, _ = w.Write([]byte(" and appended"))
+	_ = w.Close()
+	if b, _ := io.ReadAll(f); len(b) != 2<<10 || b[0] != 'c' {
+		t.Fatalf("Reader opened before the write read %d bytes starting with %q", len(b), b[:1])
+	}
+}
+
+func TestMemFSAppendResolvesOnClose(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetString("file", "old")
+
+	appendString := func(w io.WriteCloser, s string) {
+		t.Helper()
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}
+
+	// The file is replaced between Append and Close.
+	w1, _ := fs.Append("file")
+	w2, _ := fs.Append("file")
+	if err := fs.Remove("file"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	if err := WriteString(fs, "file", "new"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	appendString(w2, "2")
+	appendString(w1, "1")
+	if b, _ := ReadFile(fs, "file"); string(b) != "new21" {
+		t.Fatalf("ReadFile() returned %q, want %q", b, "new21")
+	}
+
+	// Two appends to a file that does not exist yet both land.
+	w1, _ = fs.Append("missing")
+	w2, _ = fs.Append("missing")
+	appendString(w2, "b")
+	appendString(w1, "a")
+	if b, _ := ReadFile(fs, "missing"); string(b) != "ba" {
+		t.Fatalf("ReadFile() returned %q, want %q", b, "ba")
+	}
+}
+
+// TestMemFSConcurrentAppendCreate is meant to be run with -race.
+func TestMemFSConcurrentAppendCreate(t *testing.T) {
+	fs := &MemFS{}
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w, _ := fs.Append("file")
+				_, _ = w.Write([]byte("ab"))
+				_ = w.Close()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			_ = WriteString(fs, "file", "")
+		}
+	}()
+	wg.Wait()
+
+	b, err := ReadFile(fs, "file")
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if len(b)%2 != 0 || strings.ReplaceAll(string(b), "ab", "") != "" {
+		t.Fatalf("ReadFile() returned %q, want whole appends", b)
+	}
+}
+
+func TestMemFSRenameKeepsBytes(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetBytes("big", make([]byte, 1<<20))
+	before := fs.root.Get("big").B
+
+	if err := fs.Rename("big", "dir/moved"); err != nil {
+		t.Fatalf("Rename() error: %v", err)
+	}
+	after := fs.root.Get(nameToPath("dir/moved")...).B
+	if &after[0] != &before[0] {
+		t.Fatalf("Rename() copied the file contents")
+	}
+	if fs.root.Get("big") != nil {
+		t.Fatalf("Rename() left the old path in place")
+	}
+}
+
+func TestMemFSRenameFailureLeavesTree(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{"dir/file": "x", "file": "y"})
+	dirs := fs.NumDirs()
+	if err := fs.Rename("dir", "dir/new/inside"); err == nil {
+		t.Fatalf("Rename() into a new directory inside itself returned nil error")
+	}
+	if err := fs.Rename("dir", "file/new/inside"); err == nil {
+		t.Fatalf("Rename() below a file returned nil error")
+	}
+	if got := fs.NumDirs(); got != dirs {
+		t.Fatalf("Failed Rename() left %d directories, want %d", got, dirs)
+	}
+}
+
+func TestMemFSMoveMerge(t *testing.T) {
+	contents := func(fs *MemFS) string {
+		var files []string
+		_ = fs.EachFile(".", func(path string) error {
+			files = append(files, path)
+			return nil
+		})
+		for i, path := range files {
+			b, _ := fs.Bytes(path)
+			files[i] += "=" + string(b)
+		}
+		return strings.Join(files, ",")
+	}
+	newFS := func() *MemFS {
+		return NewMemFSFromStrings(map[string]string{
+			"src/a":          "src",
+			"src/sub/b":      "src",
+			"src/sub/deep/c": "src",
+			"src/new/d":      "src",
+			"dst/a":          "dst",
+			"dst/keep":       "dst",
+			"dst/sub/b":      "dst",
+			"dst/sub/keep":   "dst",
+			"other":          "other",
+		})
+	}
+
+	fs := newFS()
+	if err := fs.MoveMerge("src", "dst"); err != nil {
+		t.Fatalf("MoveMerge() error: %v", err)
+	}
+	want := "dst/a=src,dst/keep=dst,dst/new/d=src,dst/sub/b=src,dst/sub/deep/c=src,dst/sub/keep=dst,other=other"
+	if got := contents(fs); got != want {
+		t.Fatalf("MoveMerge() left %s, want %s", got, want)
+	}
+	if fs.numFiles != 7 {
+		t.Fatalf("MoveMerge() left numFiles %d, want 7", fs.numFiles)
+	}
+
+	// Moving to a missing path, or a file onto a file, works like Rename.
+	fs = newFS()
+	if err := fs.MoveMerge("src/sub", "moved/sub"); err != nil {
+		t.Fatalf("MoveMerge() to a new path error: %v", err)
+	}
+	if err := fs.MoveMerge("other", "dst/keep"); err != nil {
+		t.Fatalf("MoveMerge() of a file error: %v", err)
+	}
+	want = "dst/a=dst,dst/keep=other,dst/sub/b=dst,dst/sub/keep=dst,moved/sub/b=src,moved/sub/deep/c=src,src/a=src,src/new/d=src"
+	if got := contents(fs); got != want {
+		t.Fatalf("MoveMerge() left %s, want %s", got, want)
+	}
+
+	// Conflicts are detected before anything is moved.
+	fs = newFS()
+	fs.SetString("dst/sub/deep", "file")
+	before, dirs := contents(fs), fs.NumDirs()
+	if err := fs.MoveMerge("src", "dst"); err == nil {
+		t.Fatalf("MoveMerge() onto a conflicting file returned nil error")
+	}
+	if err := fs.MoveMerge("other", "dst"); err == nil {
+		t.Fatalf("MoveMerge() of a file onto a directory returned nil error")
+	}
+	if err := fs.MoveMerge("dst", "dst/sub/inner"); err == nil {
+		t.Fatalf("MoveMerge() into itself returned nil error")
+	}
+	if err := fs.MoveMerge("dst", "dst/sub/new/inner"); err == nil {
+		t.Fatalf("MoveMerge() into a new directory inside itself returned nil error")
+	}
+	if err := fs.MoveMerge("src", "other/inner"); err == nil {
+		t.Fatalf("MoveMerge() below a file returned nil error")
+	}
+	if got := contents(fs); got != before {
+		t.Fatalf("Failed MoveMerge() changed the tree to %s", got)
+	}
+	if got := fs.NumDirs(); got != dirs {
+		t.Fatalf("Failed MoveMerge() left %d directories, want %d", got, dirs)
+	}
+	if err := fs.MoveMerge("missing", "dst"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("MoveMerge() of a missing path returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemFSMaxFiles(t *testing.T) {
+	const n = 3
+	fs := NewMemFS(nil, WithMaxFiles(n))
+	for i := 0; i < n; i++ {
+		name := "dir/file" + string(rune('0'+i))
+		w, err := fs.Create(name)
+		if err != nil {
+			t.Fatalf("Create(%s) error: %v", name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close() error: %v", name, err)
+		}
+	}
+	if _, err := fs.Create("extra"); !errors.Is(err, ErrTooManyFiles) {
+		t.Fatalf("Create() past the limit returned %v, want ErrTooManyFiles", err)
+	}
+	if _, err := fs.OpenFile("extra", os.O_WRONLY|os.O_CREATE, 0666); !errors.Is(err, ErrTooManyFiles) {
+		t.Fatalf("OpenFile() past the limit returned %v, want ErrTooManyFiles", err)
+	}
+	if err := fs.Symlink("dir/file0", "link"); !errors.Is(err, ErrTooManyFiles) {
+		t.Fatalf("Symlink() past the limit returned %v, want ErrTooManyFiles", err)
+	}
+
+	// Existing files can still be written to.
+	fs.SetString("dir/file0", "data")
+	if b, _ := fs.Bytes("dir/file0"); string(b) != "data" {
+		t.Fatalf("Rewriting an existing file stored %q", b)
+	}
+
+	if err := fs.Remove("dir/file0"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	if w, err := fs.Create("extra"); err != nil {
+		t.Fatalf("Create() after Remove() returned %v", err)
+	} else if err := w.Close(); err != nil {
+		t.Fatalf("Close() after Remove() returned %v", err)
+	}
+	if err := fs.RemoveAll("dir"); err != nil {
+		t.Fatalf("RemoveAll() error: %v", err)
+	}
+	if err := fs.Touch("a"); err != nil {
+		t.Fatalf("Touch() after RemoveAll() returned %v", err)
+	}
+	if err := fs.Touch("b"); err != nil {
+		t.Fatalf("Touch() after RemoveAll() returned %v", err)
+	}
+	if err := fs.Touch("c"); !errors.Is(err, ErrTooManyFiles) {
+		t.Fatalf("Touch() past the limit returned %v, want ErrTooManyFiles", err)
+	}
+}
+
+func TestRename(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		for file, s := range map[string]string{"a": "a", "b": "b", "dir/c": "c", "dir/sub/d": "d"} {
+			if err := WriteString(fs, file, s); err != nil {
+				t.Fatalf("%s: WriteString() error: %v", name, err)
+			}
+		}
+		assertContents := func(file, want string) {
+			t.Helper()
+			if b, err := ReadFile(fs, file); err != nil || string(b) != want {
+				t.Fatalf("%s: ReadFile(%s) returned %q, %v, want %q", name, file, b, err, want)
+			}
+		}
+
+		if err := Rename(fs, "a", "new/a"); err != nil {
+			t.Fatalf("%s: Rename() error: %v", name, err)
+		}
+		assertContents("new/a", "a")
+		if _, err := fs.Open("a"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: Open(a) after Rename() returned %v, want ErrNotFound", name, err)
+		}
+
+		if err := Rename(fs, "b", "new/a"); err != nil {
+			t.Fatalf("%s: Rename() onto existing file error: %v", name, err)
+		}
+		assertContents("new/a", "b")
+
+		if err := Rename(fs, "dir", "moved"); err != nil {
+			t.Fatalf("%s: Rename() of directory error: %v", name, err)
+		}
+		assertContents("moved/c", "c")
+		assertContents("moved/sub/d", "d")
+
+		if err := Rename(fs, "moved", "moved/sub/inside"); err == nil {
+			t.Fatalf("%s: Rename() of directory into itself returned nil error", name)
+		}
+		if err := Rename(fs, "missing", "x"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: Rename(missing) returned %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestMemFSWriterBufferReuse(t *testing.T) {
+	fs := &MemFS{}
+	for i := 0; i < 100; i++ {
+		name := "file" + strconv.Itoa(i)
+		op := fs.Create
+		if i%2 == 1 {
+			op = fs.Append
+		}
+		w, _ := op(name)
+		_, _ = w.Write([]byte(name))
+		_ = w.Close()
+	}
+	// Committed contents must not alias buffers that later writers reuse.
+	for i := 0; i < 100; i++ {
+		name := "file" + strconv.Itoa(i)
+		if b, _ := fs.Bytes(name); string(b) != name {
+			t.Fatalf("Bytes(%s) returned %q", name, b)
+		}
+		if node := fs.root.Get(name); cap(node.B) != len(node.B) {
+			t.Fatalf("%s has capacity %d for %d bytes, want a copy of the buffer", name, cap(node.B), len(node.B))
+		}
+	}
+}
+
+func TestMemFSPathEscape(t *testing.T) {
+	fs := &MemFS{}
+	if err := WriteString(fs, "dir/file", "file"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	for _, name := range []string{"..", "../x", "dir/../../x"} {
+		if err := WriteString(fs, name, "x"); !errors.Is(err, ErrPathEscape) {
+			t.Fatalf("WriteString(%s) returned %v, want ErrPathEscape", name, err)
+		}
+		if _, err := fs.Append(name); !errors.Is(err, ErrPathEscape) {
+			t.Fatalf("Append(%s) returned %v, want ErrPathEscape", name, err)
+		}
+		if err := fs.MkdirAll(name); !errors.Is(err, ErrPathEscape) {
+			t.Fatalf("MkdirAll(%s) returned %v, want ErrPathEscape", name, err)
+		}
+		if err := fs.Rename("dir/file", name); !errors.Is(err, ErrPathEscape) {
+			t.Fatalf("Rename(dir/file, %s) returned %v, want ErrPathEscape", name, err)
+		}
+		if err := fs.MoveMerge("dir", name); !errors.Is(err, ErrPathEscape) {
+			t.Fatalf("MoveMerge(dir, %s) returned %v, want ErrPathEscape", name, err)
+		}
+		if err := fs.Remove(name); !errors.Is(err, ErrPathEscape) {
+			t.Fatalf("Remove(%s) returned %v, want ErrPathEscape", name, err)
+		}
+		if _, err := fs.Open(name); !errors.Is(err, ErrPathEscape) {
+			t.Fatalf("Open(%s) returned %v, want ErrPathEscape", name, err)
+		}
+	}
+	if names, _ := fs.ReadFileNames("."); len(names) != 0 {
+		t.Fatalf("ReadFileNames(.) returned %v, want no files", names)
+	}
+	entries, err := fs.ReadDir(".")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "dir" {
+		t.Fatalf("ReadDir(.) returned %v, %v, want only dir", entries, err)
+	}
+	if b, err := fs.Bytes("dir/file"); err != nil || string(b) != "file" {
+		t.Fatalf("Bytes(dir/file) returned %q, %v, want file", b, err)
+	}
+}
+
+func BenchmarkMemFSCreate(b *testing.B) {
+	fs := &MemFS{}
+	chunk := bytes.Repeat([]byte("x"), 256)
+	names := make([]string, 100)
+	for i := range names {
+		names[i] = "dir/file" + strconv.Itoa(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w, _ := fs.Create(names[i%len(names)])
+		for j := 0; j < 16; j++ {
+			_, _ = w.Write(chunk)
+		}
+		_ = w.Close()
+	}
+}
@@ -0,0 +1,44 @@
+package simplefs
+
+import (
+	"path"
+	"sort"
+	"time"
+)
+
+// FilesModifiedBetween returns the sorted paths of all files in the tree
+// rooted at root whose modification time is in [start, end). Directories are
+// not included. Modification times are read from the DirEntry.Info of the
+// directory listings, so no additional Stat calls are made.
+func FilesModifiedBetween(fs FS, root string, start, end time.Time) ([]string, error) {
+	var names []string
+	if err := filesModifiedBetween(fs, root, start, end, &names); err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func filesModifiedBetween(fs FS, dir string, start, end time.Time, names *[]string) error {
+	entries, err := fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if err := filesModifiedBetween(fs, name, start, end, names); err != nil {
+				return err
+			}
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if modTime := info.ModTime(); !modTime.Before(start) && modTime.Before(end) {
+			*names = append(*names, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package simplefs
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilesModifiedBetween(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	modTimes := map[string]time.Time{
+		"before":      base.Add(-time.Hour),
+		"start":       base,
+		"dir/inside":  base.Add(30 * time.Minute),
+		"dir/sub/end": base.Add(time.Hour),
+		"after":       base.Add(2 * time.Hour),
+	}
+
+	mem := &MemFS{}
+	dir := t.TempDir()
+	for name, modTime := range modTimes {
+		mem.SetString(name, name)
+		mem.root.Get(nameToPath(name)...).ModTime = modTime
+		if err := WriteString(OsFS(dir), name, name); err != nil {
+			t.Fatalf("WriteString() error: %v", err)
+		}
+		if err := os.Chtimes(path.Join(dir, name), modTime, modTime); err != nil {
+			t.Fatalf("Chtimes() error: %v", err)
+		}
+	}
+
+	for name, fs := range map[string]FS{"MemFS": mem, "OsFS": OsFS(dir)} {
+		got, err := FilesModifiedBetween(fs, ".", base, base.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("%s: FilesModifiedBetween() error: %v", name, err)
+		}
+		if strings.Join(got, ",") != "dir/inside,start" {
+			t.Fatalf("%s: FilesModifiedBetween() returned %v", name, got)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package simplefs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// MountFS composes file systems by mounting them under path prefixes. Each
+// operation is routed to the FS mounted at the longest prefix of the path,
+// with the prefix removed, so that with one FS mounted at "data" and another
+// at "data/cache", "data/cache/x" is "x" in the second. Paths that are not
+// under any mount point are routed to Default, or return ErrNotFound if
+// Default is nil.
+//
+// Mount points and their parent directories are listed as directories by
+// ReadDir, merged with the entries of the FS the directory belongs to. A mount
+// point hides whatever the parent FS has at that path.
+//
+// The zero value is an empty MountFS ready to use.
+type MountFS struct {
+	Default FS
+
+	l      sync.RWMutex
+	mounts map[string]FS
+}
+
+// Mount mounts fs under prefix. The root cannot be a mount point; set Default
+// instead. Mounting at a prefix that is already in use returns an error
+// wrapping ErrExist.
+func (fs *MountFS) Mount(prefix string, mounted FS) error {
+	prefix = CleanPath(prefix)
+	if prefix == "." {
+		return fmt.Errorf("cannot mount at the root directory. Use MountFS.Default instead")
+	}
+	if prefix == ".." || strings.HasPrefix(prefix, "../") {
+		return fmt.Errorf("cannot mount at '%s'. Path is outside the root directory", prefix)
+	}
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	if _, ok := fs.mounts[prefix]; ok {
+		return &FSError{Op: "mount", Path: prefix, Err: ErrExist}
+	}
+	if fs.mounts == nil {
+		fs.mounts = map[string]FS{}
+	}
+	fs.mounts[prefix] = mounted
+	return nil
+}
+
+// resolve returns the FS that name is routed to and the name within it. It
+// returns a nil FS if name is not under any mount point and there is no
+// Default.
+func (fs *MountFS) resolve(name string) (FS, string) {
+	parts := nameToPath(name)
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+	for i := len(parts); i > 0; i-- {
+		if mounted, ok := fs.mounts[strings.Join(parts[:i], "/")]; ok {
+			rel := "."
+			if i < len(parts) {
+				rel = strings.Join(parts[i:], "/")
+			}
+			return mounted, rel
+		}
+	}
+	return fs.Default, name
+}
+
+// mountEntries returns the names of the entries that mount points add to the
+// named directory: the mount points directly in it, and the directories
+// leading to mount points further down. It reports whether name is a mount
+// point itself.
+func (fs *MountFS) mountEntries(name string) (names map[string]bool, isMount bool) {
+	dir := CleanPath(name)
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+	names = map[string]bool{}
+	for prefix := range fs.mounts {
+		rest := prefix
+		if dir != "." {
+			if prefix == dir {
+				isMount = true
+			}
+			if !strings.HasPrefix(prefix, dir+"/") {
+				continue
+			}
+			rest = strings.TrimPrefix(prefix, dir+"/")
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i]
+		}
+		names[rest] = true
+	}
+	return names, isMount
+}
+
+func (fs *MountFS) Open(name string) (File, error) {
+	if names, _ := fs.mountEntries(name); len(names) > 0 {
+		info, err := fs.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		return &mountDir{fs: fs, name: name, info: info}, nil
+	}
+	target, rel := fs.resolve(name)
+	if target == nil {
+		return nil, notFound("open", name)
+	}
+	return target.Open(rel)
+}
+
+// Stat returns a FileInfo describing the named file. Mount points, and
+// directories that only exist because a mount point is beneath them, are
+// described as plain directories.
+func (fs *MountFS) Stat(name string) (os.FileInfo, error) {
+	names, isMount := fs.mountEntries(name)
+	if !isMount {
+		target, rel := fs.resolve(name)
+		if target == nil && len(names) == 0 {
+			return nil, notFound("stat", name)
+		}
+		if target != nil {
+			info, err := Stat(target, rel)
+			if len(names) == 0 || err == nil && info.IsDir() {
+				return info, err
+			}
+		}
+	}
+	return &fileInfo{name: path.Base(CleanPath(name)), isDir: true, mode: os.ModeDir | 0777}, nil
+}
+
+// ReadDir lists the named directory in the FS it is routed to, together with
+// the mount points and directories leading to mount points in it.
+func (fs *MountFS) ReadDir(name string) ([]DirEntry, error) {
+	names, isMount := fs.mountEntries(name)
+	var entries []DirEntry
+	var err error
+	if target, rel := fs.resolve(name); target != nil {
+		entries, err = target.ReadDir(rel)
+	} else {
+		err = notFound("readdir", name)
+	}
+	if err != nil && (len(names) == 0 && !isMount || !errors.Is(err, ErrNotFound)) {
+		return nil, err
+	}
+
+	merged := make([]DirEntry, 0, len(entries)+len(names))
+	for _, entry := range entries {
+		if !names[entry.Name()] {
+			merged = append(merged, entry)
+		}
+	}
+	for n := range names {
+		merged = append(merged, &dirEntry{name: n, isDir: true})
+	}
+	sortDirEntries(merged)
+	return merged, nil
+}
+
+func (fs *MountFS) Create(name string) (io.WriteCloser, error) {
+	target, rel, err := fs.resolveFile("create", name)
+	if err != nil {
+		return nil, err
+	}
+	return target.Create(rel)
+}
+
+func (fs *MountFS) Append(name string) (io.WriteCloser, error) {
+	target, rel, err := fs.resolveFile("append", name)
+	if err != nil {
+		return nil, err
+	}
+	return target.Append(rel)
+}
+
+// resolveFile is like resolve, but returns an error if name cannot be a file
+// because it is a mount point or a directory leading to one.
+func (fs *MountFS) resolveFile(op, name string) (FS, string, error) {
+	if names, isMount := fs.mountEntries(name); isMount || len(names) > 0 {
+		return nil, "", fmt.Errorf("cannot %s '%s'. Path is a mount point or contains one", op, name)
+	}
+	target, rel := fs.resolve(name)
+	if target == nil {
+		return nil, "", notFound(op, name)
+	}
+	return target, rel, nil
+}
+
+// mountDir is a directory opened from a MountFS that contains mount points.
+type mountDir struct {
+	fs             *MountFS
+	name           string
+	info           os.FileInfo
+	readDirEntries []DirEntry
+}
+
+func (dir *mountDir) Stat() (os.FileInfo, error) {
+	return dir.info, nil
+}
+
+func (dir *mountDir) Read(p []byte) (n int, err error) {
+	return 0, &FSError{Op: "read", Path: dir.name, Err: ErrIsDir}
+}
+
+func (dir *mountDir) Close() error {
+	return nil
+}
+
+func (dir *mountDir) ReadDir(n int) ([]DirEntry, error) {
+	if dir.readDirEntries == nil {
+		entries, err := dir.fs.ReadDir(dir.name)
+		if err != nil {
+			return nil, err
+		}
+		dir.readDirEntries = entries
+	}
+	return nextDirEntries(&dir.readDirEntries, n)
+}
@@ -0,0 +1,121 @@
+package simplefs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMountFS(t *testing.T) {
+	base := NewMemFSFromStrings(map[string]string{"readme": "base", "data/hidden": "base", "a/b/file": "base"})
+	data := NewMemFSFromStrings(map[string]string{"file": "data", "cache/hidden": "data"})
+	cache := NewMemFSFromStrings(map[string]string{"file": "cache"})
+	assets := NewMemFSFromStrings(map[string]string{"logo": "assets"})
+
+	fs := &MountFS{Default: base}
+	for prefix, mounted := range map[string]FS{"data": data, "data/cache": cache, "a/b/assets": assets} {
+		if err := fs.Mount(prefix, mounted); err != nil {
+			t.Fatalf("Mount(%s) error: %v", prefix, err)
+		}
+	}
+	if err := fs.Mount("./data/", data); !errors.Is(err, ErrExist) {
+		t.Fatalf("Mount() on existing prefix returned %v, want ErrExist", err)
+	}
+	if err := fs.Mount(".", data); err == nil {
+		t.Fatalf("Mount() at the root returned nil error")
+	}
+
+	t.Run("Open", func(t *testing.T) {
+		for name, want := range map[string]string{
+			"readme":          "base",
+			"a/b/file":        "base",
+			"data/file":       "data",
+			"data/cache/file": "cache",
+			"a/b/assets/logo": "assets",
+		} {
+			b, err := ReadFile(fs, name)
+			if err != nil || string(b) != want {
+				t.Fatalf("ReadFile(%s) returned %q, %v, want %q", name, b, err, want)
+			}
+		}
+		for _, name := range []string{"data/hidden", "data/cache/hidden", "missing"} {
+			if _, err := fs.Open(name); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Open(%s) returned %v, want ErrNotFound", name, err)
+			}
+		}
+	})
+
+	t.Run("ReadDir", func(t *testing.T) {
+		tests := map[string]string{
+			".":          "[dir(a) dir(data) file(readme)]",
+			"a":          "[dir(b)]",
+			"a/b":        "[dir(assets) file(file)]",
+			"a/b/assets": "[file(logo)]",
+			"data":       "[dir(cache) file(file)]",
+			"data/cache": "[file(file)]",
+		}
+		for name, want := range tests {
+			entries, err := fs.ReadDir(name)
+			if err != nil {
+				t.Fatalf("ReadDir(%s) error: %v", name, err)
+			}
+			if got := fmt.Sprint(entries); got != want {
+				t.Fatalf("ReadDir(%s) returned %s, want %s", name, got, want)
+			}
+
+			dir, err := fs.Open(name)
+			if err != nil {
+				t.Fatalf("Open(%s) error: %v", name, err)
+			}
+			entries, err = dir.ReadDir(-1)
+			if err != nil {
+				t.Fatalf("Open(%s).ReadDir() error: %v", name, err)
+			}
+			if got := fmt.Sprint(entries); got != want {
+				t.Fatalf("Open(%s).ReadDir() returned %s, want %s", name, got, want)
+			}
+		}
+	})
+
+	t.Run("Stat", func(t *testing.T) {
+		for _, name := range []string{"a", "data", "data/cache", "a/b/assets"} {
+			info, err := fs.Stat(name)
+			if err != nil || !info.IsDir() {
+				t.Fatalf("Stat(%s) returned %v, %v, want a directory", name, info, err)
+			}
+		}
+		if info, err := fs.Stat("data/file"); err != nil || info.IsDir() || info.Size() != 4 {
+			t.Fatalf("Stat(data/file) returned %v, %v", info, err)
+		}
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		w, err := fs.Create("data/cache/new")
+		if err != nil {
+			t.Fatalf("Create() error: %v", err)
+		}
+		_, _ = w.Write([]byte("new"))
+		_ = w.Close()
+		if b, err := cache.Bytes("new"); err != nil || string(b) != "new" {
+			t.Fatalf("Mounted FS contains %q, %v, want %q", b, err, "new")
+		}
+		if _, err := fs.Create("data/cache"); err == nil {
+			t.Fatalf("Create() on a mount point returned nil error")
+		}
+	})
+
+	t.Run("No default", func(t *testing.T) {
+		fs := &MountFS{}
+		_ = fs.Mount("data", data)
+		if _, err := fs.Open("other"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Open() outside mount points returned %v, want ErrNotFound", err)
+		}
+		if _, err := fs.Create("other"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Create() outside mount points returned %v, want ErrNotFound", err)
+		}
+		entries, err := fs.ReadDir(".")
+		if err != nil || fmt.Sprint(entries) != "[dir(data)]" {
+			t.Fatalf("ReadDir(.) returned %v, %v", entries, err)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package simplefs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var ErrNameTooLong = fmt.Errorf("name too long")
+
+// WithNameLimits returns an FS that rejects Create, Append and Rename targets
+// whose path segments are longer than maxNameLen bytes, or whose full path
+// is longer than maxPathLen bytes, with ErrNameTooLong. A limit <= 0 disables
+// the corresponding check. Reads are passed through unchanged.
+func WithNameLimits(fs FS, maxNameLen, maxPathLen int) FS {
+	return &nameLimitFS{fs: fs, maxNameLen: maxNameLen, maxPathLen: maxPathLen}
+}
+
+type nameLimitFS struct {
+	fs         FS
+	maxNameLen int
+	maxPathLen int
+}
+
+func (fs *nameLimitFS) check(name string) error {
+	if fs.maxPathLen > 0 && len(name) > fs.maxPathLen {
+		return fmt.Errorf("%w: '%s' is longer than %d bytes", ErrNameTooLong, name, fs.maxPathLen)
+	}
+	if fs.maxNameLen > 0 {
+		for _, segment := range strings.Split(name, "/") {
+			if len(segment) > fs.maxNameLen {
+				return fmt.Errorf("%w: '%s' in '%s' is longer than %d bytes", ErrNameTooLong, segment, name, fs.maxNameLen)
+			}
+		}
+	}
+	return nil
+}
+
+func (fs *nameLimitFS) Unwrap() FS {
+	return fs.fs
+}
+
+func (fs *nameLimitFS) Open(name string) (File, error) {
+	return fs.fs.Open(name)
+}
+
+func (fs *nameLimitFS) ReadDir(name string) ([]DirEntry, error) {
+	return fs.fs.ReadDir(name)
+}
+
+func (fs *nameLimitFS) Stat(name string) (os.FileInfo, error) {
+	return Stat(fs.fs, name)
+}
+
+func (fs *nameLimitFS) Lstat(name string) (os.FileInfo, error) {
+	return Lstat(fs.fs, name)
+}
+
+func (fs *nameLimitFS) Create(name string) (io.WriteCloser, error) {
+	if err := fs.check(name); err != nil {
+		return nil, err
+	}
+	return fs.fs.Create(name)
+}
+
+func (fs *nameLimitFS) Append(name string) (io.WriteCloser, error) {
+	if err := fs.check(name); err != nil {
+		return nil, err
+	}
+	return fs.fs.Append(name)
+}
+
+func (fs *nameLimitFS) Rename(oldName, newName string) error {
+	if err := fs.check(newName); err != nil {
+		return err
+	}
+	return Rename(fs.fs, oldName, newName)
+}
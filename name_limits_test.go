@@ -0,0 +1,33 @@
+package simplefs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithNameLimits(t *testing.T) {
+	tests := map[string]bool{
+		"abcde":         true,
+		"abcde/abcde":   true,
+		"abcdef":        false, // segment too long
+		"abc/abcdef":    false, // segment too long
+		"abcd/abcd/abc": false, // path too long
+	}
+	for name, ok := range tests {
+		fs := WithNameLimits(&MemFS{}, 5, 12)
+		_, createErr := fs.Create(name)
+		_, appendErr := fs.Append(name)
+		if err := WriteString(fs, "src", ""); err != nil {
+			t.Fatalf("WriteString() error: %v", err)
+		}
+		renameErr := Rename(fs, "src", name)
+		for _, err := range []error{createErr, appendErr, renameErr} {
+			if ok && err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if !ok && !errors.Is(err, ErrNameTooLong) {
+				t.Fatalf("%s: got error %v, want ErrNameTooLong", name, err)
+			}
+		}
+	}
+}
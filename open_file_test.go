@@ -0,0 +1,203 @@
+package simplefs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestOpenFile(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		t.Run(name, func(t *testing.T) {
+			testOpenFile(t, fs)
+		})
+	}
+}
+
+func testOpenFile(t *testing.T, fs FS) {
+	write := func(name string, flag int, s string) {
+		t.Helper()
+		f, err := OpenFile(fs, name, flag, 0666)
+		if err != nil {
+			t.Fatalf("OpenFile(%s, %#x) error: %v", name, flag, err)
+		}
+		if _, err := f.(io.Writer).Write([]byte(s)); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}
+	assertContents := func(name, want string) {
+		t.Helper()
+		if b, err := ReadFile(fs, name); err != nil || string(b) != want {
+			t.Fatalf("ReadFile(%s) returned %q, %v, want %q", name, b, err, want)
+		}
+	}
+
+	if _, err := OpenFile(fs, "file", os.O_RDWR, 0666); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("OpenFile() on missing file without O_CREATE returned %v, want ErrNotFound", err)
+	}
+
+	write("dir/file", os.O_WRONLY|os.O_CREATE, "hello world")
+	assertContents("dir/file", "hello world")
+
+	t.Run("O_EXCL", func(t *testing.T) {
+		_, err := OpenFile(fs, "dir/file", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if !errors.Is(err, ErrExist) {
+			t.Fatalf("OpenFile() with O_EXCL on existing file returned %v, want ErrExist", err)
+		}
+		var fsErr *FSError
+		if !errors.As(err, &fsErr) || fsErr.Op != "open" || fsErr.Path != "dir/file" {
+			t.Fatalf("OpenFile() with O_EXCL on existing file returned %#v, want an FSError naming the file", err)
+		}
+		write("dir/new", os.O_WRONLY|os.O_CREATE|os.O_EXCL, "new")
+		assertContents("dir/new", "new")
+	})
+
+	t.Run("O_RDWR", func(t *testing.T) {
+		f, err := OpenFile(fs, "dir/file", os.O_RDWR, 0666)
+		if err != nil {
+			t.Fatalf("OpenFile() error: %v", err)
+		}
+		b := make([]byte, 5)
+		if _, err := io.ReadFull(f, b); err != nil || string(b) != "hello" {
+			t.Fatalf("Read() returned %q, %v", b, err)
+		}
+		if _, err := f.(io.Seeker).Seek(6, io.SeekStart); err != nil {
+			t.Fatalf("Seek() error: %v", err)
+		}
+		if _, err := f.(io.Writer).Write([]byte("WORLD")); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+		assertContents("dir/file", "hello WORLD")
+	})
+
+	t.Run("O_APPEND", func(t *testing.T) {
+		write("dir/file", os.O_WRONLY|os.O_APPEND, "!")
+		assertContents("dir/file", "hello WORLD!")
+	})
+
+	t.Run("O_TRUNC", func(t *testing.T) {
+		write("dir/file", os.O_WRONLY|os.O_TRUNC, "bye")
+		assertContents("dir/file", "bye")
+	})
+
+	t.Run("Read-only", func(t *testing.T) {
+		f, err := OpenFile(fs, "dir/file", os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("OpenFile() error: %v", err)
+		}
+		defer func() { _ = f.Close() }()
+		if _, err := f.(io.Writer).Write([]byte("x")); err == nil {
+			t.Fatalf("Write() to read-only file returned nil error")
+		}
+	})
+}
+
+func TestMemFSOpenFileCloseOnDir(t *testing.T) {
+	fs := &MemFS{}
+	fs.SetString("file", "abc")
+	f, err := fs.OpenFile("file", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error: %v", err)
+	}
+	if _, err := f.(io.Writer).Write([]byte("x")); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := fs.Remove("file"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	if err := fs.MkdirAll("file"); err != nil {
+		t.Fatalf("MkdirAll() error: %v", err)
+	}
+	if err := f.Close(); !errors.Is(err, ErrIsDir) {
+		t.Fatalf("Close() after the file was replaced by a directory returned %v, want ErrIsDir", err)
+	}
+	if info, err := fs.Stat("file"); err != nil || !info.IsDir() {
+		t.Fatalf("Stat() returned %v, %v, want the directory", info, err)
+	}
+}
+
+func TestWriteAt(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		if err := WriteString(fs, "file", "hello"); err != nil {
+			t.Fatalf("%s: WriteString() error: %v", name, err)
+		}
+		f, err := OpenFile(fs, "file", os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("%s: OpenFile() error: %v", name, err)
+		}
+		if _, err := f.(io.WriterAt).WriteAt([]byte("abc"), 10); err != nil {
+			t.Fatalf("%s: WriteAt() error: %v", name, err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("%s: Close() error: %v", name, err)
+		}
+		b, err := ReadFile(fs, "file")
+		if err != nil {
+			t.Fatalf("%s: ReadFile() error: %v", name, err)
+		}
+		if want := "hello\x00\x00\x00\x00\x00abc"; string(b) != want {
+			t.Fatalf("%s: ReadFile() returned %q, want %q", name, b, want)
+		}
+	}
+}
+
+func TestCreateExcl(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		t.Run(name, func(t *testing.T) {
+			w, err := CreateExcl(fs, "dir/lock")
+			if err != nil {
+				t.Fatalf("CreateExcl() error: %v", err)
+			}
+			if _, err := w.Write([]byte("owner")); err != nil {
+				t.Fatalf("Write() error: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error: %v", err)
+			}
+			if b, err := ReadFile(fs, "dir/lock"); err != nil || string(b) != "owner" {
+				t.Fatalf("ReadFile() returned %q, %v, want %q", b, err, "owner")
+			}
+			if _, err := CreateExcl(fs, "dir/lock"); !errors.Is(err, ErrExist) {
+				t.Fatalf("CreateExcl() on existing file returned %v, want ErrExist", err)
+			}
+			if _, err := CreateExcl(fs, "dir"); !errors.Is(err, ErrExist) {
+				t.Fatalf("CreateExcl() on existing directory returned %v, want ErrExist", err)
+			}
+
+			const n = 50
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			var won, lost int
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					w, err := CreateExcl(fs, "race")
+					mu.Lock()
+					defer mu.Unlock()
+					switch {
+					case err == nil:
+						won++
+						_ = w.Close()
+					case errors.Is(err, ErrExist):
+						lost++
+					default:
+						t.Errorf("CreateExcl() error: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if won != 1 || lost != n-1 {
+				t.Fatalf("%d CreateExcl() calls succeeded and %d failed, want 1 and %d", won, lost, n-1)
+			}
+		})
+	}
+}
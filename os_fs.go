@@ -1,49 +1,340 @@
 package simplefs
 
 import (
+	"bufio"
+	"errors"
 	"io"
+	iofs "io/fs"
 	"io/ioutil"
 	"os"
-	"path"
+	"path/filepath"
+	"syscall"
+	"time"
 )
 
 type osFs struct {
-	dir string
+	dir          string
+	directSync   bool
+	writeBuffer  int
+	atomicCreate bool
+	dirSync      bool
 }
 
-func OsFS(dir string) FS {
-	return &osFs{dir: dir}
+// OsOption configures the FS returned by OsFS.
+type OsOption func(fs *osFs)
+
+// WithDirectSync makes Create, CreateMode and Append open files with O_SYNC,
+// so that every Write is durable on disk before it returns. This is stronger
+// than syncing once on close, and correspondingly slower: each Write waits
+// for the device, so prefer few large writes over many small ones. It has no
+// equivalent for MemFS, where data is never on disk.
+func WithDirectSync() OsOption {
+	return func(fs *osFs) {
+		fs.directSync = true
+	}
+}
+
+// WithWriteBuffer makes the writers returned by Create, CreateMode and Append
+// buffer writes in memory, up to size bytes, so that many small writes cost
+// few syscalls. The buffer is flushed when it is full, on Sync and on Close,
+// which reports any error from flushing. MemFS needs no such option, as its
+// writers always buffer until Close.
+func WithWriteBuffer(size int) OsOption {
+	return func(fs *osFs) {
+		fs.writeBuffer = size
+	}
+}
+
+// OsFS returns an FS backed by the OS directory dir. Names are resolved
+// inside dir, and names that lead outside it, with ".." elements or through
+// symbolic links, are rejected with an error wrapping ErrPathEscape. Like
+// all names in this package they are separated by forward slashes, and are
+// converted to the separator of the OS with filepath.FromSlash; dir itself is
+// an OS path.
+func OsFS(dir string, opts ...OsOption) FS {
+	fs := &osFs{dir: dir}
+	for _, opt := range opts {
+		opt(fs)
+	}
+	return fs
+}
+
+// openForWrite opens the named file for writing, creating its parent
+// directories as needed.
+func (fs *osFs) openForWrite(op, name string, flag int, perm os.FileMode) (*os.File, error) {
+	p, err := fs.path(op, name, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0666); err != nil {
+		return nil, err
+	}
+	if fs.directSync {
+		flag |= os.O_SYNC
+	}
+	f, err := os.OpenFile(p, flag, perm)
+	if errors.Is(err, syscall.EISDIR) {
+		return nil, &FSError{Op: op, Path: name, Err: ErrIsDir}
+	}
+	return f, err
 }
 
 func (fs *osFs) Create(name string) (io.WriteCloser, error) {
-	p := path.Join(fs.dir, name)
-	if err := os.MkdirAll(path.Dir(p), 0666); err != nil {
+	if fs.atomicCreate {
+		return fs.createAtomic(name, 0666, false)
+	}
+	f, err := fs.openForWrite("create", name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
 		return nil, err
 	}
-	return os.Create(p)
+	return fs.writer(f), nil
+}
+
+// writer returns f, wrapped in a buffer if the FS was configured with
+// WithWriteBuffer.
+func (fs *osFs) writer(f *os.File) io.WriteCloser {
+	if fs.writeBuffer <= 0 {
+		return f
+	}
+	return &bufferedFile{w: bufio.NewWriterSize(f, fs.writeBuffer), f: f}
+}
+
+// CreateMode is like Create but sets the file's mode to the given mode. The
+// mode is applied with Chmod so that it is not subject to the umask and also
+// applies when the file already exists.
+func (fs *osFs) CreateMode(name string, mode os.FileMode) (io.WriteCloser, error) {
+	if fs.atomicCreate {
+		return fs.createAtomic(name, mode.Perm(), true)
+	}
+	f, err := fs.openForWrite("create", name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(mode.Perm()); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return fs.writer(f), nil
+}
+
+// CreateExcl implements ExclCreator by opening the file with
+// os.O_CREATE|os.O_EXCL.
+func (fs *osFs) CreateExcl(name string) (io.WriteCloser, error) {
+	f, err := fs.openForWrite("create", name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, &FSError{Op: "create", Path: name, Err: ErrExist}
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// CreateSparse creates the named file with the given size by truncating it,
+// which leaves a hole rather than writing zeros on file systems that support
+// sparse files.
+func (fs *osFs) CreateSparse(name string, size int64) error {
+	f, err := fs.openForWrite("create", name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(size); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Touch creates the named file empty if it does not exist, and otherwise sets
+// its access and modification times to now with os.Chtimes.
+func (fs *osFs) Touch(name string) error {
+	p, err := fs.path("touch", name, true)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = os.Chtimes(p, now, now)
+	if err == nil || !os.IsNotExist(err) {
+		return err
+	}
+	f, err := fs.openForWrite("touch", name, os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	return f.Close()
 }
 
 func (fs *osFs) Append(name string) (io.WriteCloser, error) {
-	p := path.Join(fs.dir, name)
-	if err := os.MkdirAll(path.Dir(p), 0666); err != nil {
+	f, err := fs.openForWrite("append", name, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
 		return nil, err
 	}
-	return os.OpenFile(p, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0666)
+	return fs.writer(f), nil
 }
 
 func (fs *osFs) Open(name string) (File, error) {
-	f, err := os.Open(path.Join(fs.dir, name))
+	p, err := fs.path("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil && os.IsNotExist(err) {
+		return nil, notFound("open", name)
+	}
+	return &osFile{f: f, name: name}, err
+}
+
+func (fs *osFs) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	p, err := fs.path("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if flag&os.O_CREATE != 0 {
+		if err := os.MkdirAll(filepath.Dir(p), 0666); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(p, flag, perm)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, notFound("open", name)
+		}
+		if os.IsExist(err) {
+			return nil, &FSError{Op: "open", Path: name, Err: ErrExist}
+		}
+		return nil, err
+	}
+	return &osFile{f: f, name: name}, nil
+}
+
+func (fs *osFs) Stat(name string) (os.FileInfo, error) {
+	p, err := fs.path("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(p)
+	if err != nil && os.IsNotExist(err) {
+		return nil, notFound("stat", name)
+	}
+	return info, err
+}
+
+// Lstat implements Lstater with os.Lstat.
+func (fs *osFs) Lstat(name string) (os.FileInfo, error) {
+	p, err := fs.path("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(p)
+	if err != nil && os.IsNotExist(err) {
+		return nil, notFound("lstat", name)
+	}
+	return info, err
+}
+
+// MkdirAll implements DirMaker with os.MkdirAll.
+func (fs *osFs) MkdirAll(name string) error {
+	p, err := fs.path("mkdir", name, true)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(p, 0777)
+}
+
+func (fs *osFs) Remove(name string) error {
+	p, err := fs.path("remove", name, false)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p)
 	if err != nil && os.IsNotExist(err) {
-		return nil, ErrNotFound
+		return notFound("remove", name)
+	}
+	return err
+}
+
+func (fs *osFs) RemoveAll(name string) error {
+	p, err := fs.path("remove", name, false)
+	if err != nil {
+		return err
 	}
-	return &osFile{f}, err
+	return os.RemoveAll(p)
 }
 
+// Rename renames oldName to newName with os.Rename, creating the parent
+// directories of newName as needed.
+func (fs *osFs) Rename(oldName, newName string) error {
+	oldPath, err := fs.path("rename", oldName, false)
+	if err != nil {
+		return err
+	}
+	newPath, err := fs.path("rename", newName, false)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0666); err != nil {
+		return err
+	}
+	err = os.Rename(oldPath, newPath)
+	if err != nil && os.IsNotExist(err) {
+		return notFound("rename", oldName)
+	}
+	if err != nil || !fs.dirSync {
+		return err
+	}
+	if err := syncDir(filepath.Dir(newPath)); err != nil {
+		return err
+	}
+	if filepath.Dir(oldPath) != filepath.Dir(newPath) {
+		return syncDir(filepath.Dir(oldPath))
+	}
+	return nil
+}
+
+func (fs *osFs) Symlink(target, linkName string) error {
+	p, err := fs.path("symlink", linkName, false)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0666); err != nil {
+		return err
+	}
+	return os.Symlink(filepath.FromSlash(target), p)
+}
+
+func (fs *osFs) Readlink(name string) (string, error) {
+	p, err := fs.path("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(p)
+	if err != nil && os.IsNotExist(err) {
+		return "", notFound("readlink", name)
+	}
+	return filepath.ToSlash(target), err
+}
+
+// ReadFileNames returns the sorted base names of the entries of dir that are
+// not directories. Unlike ListFiles it behaves the same for MemFS.
+func (fs *osFs) ReadFileNames(dir string) ([]string, error) {
+	return readFileNames(fs, dir)
+}
+
+// ListFiles returns the base names of the files directly in dir.
+//
+// Deprecated: MemFS.ListFiles lists the whole tree beneath dir instead, so
+// code using ListFiles behaves differently depending on the FS. Use
+// ReadFileNames for the files directly in dir, or WalkFiles for the whole
+// tree.
 func (fs *osFs) ListFiles(dir string) ([]string, error) {
-	info, err := ioutil.ReadDir(path.Join(fs.dir, dir))
+	p, err := fs.path("readdir", dir, true)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ioutil.ReadDir(p)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, ErrNotFound
+			return nil, notFound("listfiles", dir)
 		}
 		return nil, err
 	}
@@ -56,31 +347,130 @@ func (fs *osFs) ListFiles(dir string) ([]string, error) {
 	return names, nil
 }
 
+// ListDirs returns the paths of all directories beneath dir, not including
+// dir itself, in depth-first order. Symbolic links to directories are not
+// followed.
+func (fs *osFs) ListDirs(dir string) ([]string, error) {
+	root, err := fs.path("readdir", dir, true)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		if err == nil || os.IsNotExist(err) {
+			return nil, notFound("listdirs", dir)
+		}
+		return nil, err
+	}
+	var names []string
+	err = filepath.WalkDir(root, func(p string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != root {
+			rel, err := filepath.Rel(fs.dir, p)
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
 func (fs *osFs) ReadDir(name string) ([]DirEntry, error) {
-	osInfos, err := ioutil.ReadDir(path.Join(fs.dir, name))
+	p, err := fs.path("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	osInfos, err := ioutil.ReadDir(p)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, ErrNotFound
+			return nil, notFound("readdir", name)
 		}
 		return nil, err
 	}
-	fileInfos := make([]os.FileInfo, len(osInfos))
+	dirEntries := make([]DirEntry, len(osInfos))
 	for i, info := range osInfos {
-		fileInfos[i] = &fileInfo{name: info.Name(), isDir: info.IsDir()}
+		dirEntries[i] = newDirEntry(info)
 	}
-	dirEntries := make([]DirEntry, len(fileInfos))
-	for i, info := range fileInfos {
-		dirEntries[i] = &dirEntry{name: info.Name(), isDir: info.IsDir()}
+	// Sort explicitly rather than rely on ioutil.ReadDir, since the order is
+	// part of the FS contract and must match MemFS.
+	sortDirEntries(dirEntries)
+	return dirEntries, nil
+}
+
+// bufferedFile is a writer returned by an FS configured with WithWriteBuffer.
+// Like an *os.File, it returns os.ErrClosed when used after Close.
+type bufferedFile struct {
+	w      *bufio.Writer
+	f      *os.File
+	closed bool
+}
+
+func (w *bufferedFile) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
 	}
-	return dirEntries, err
+	return w.w.Write(p)
+}
+
+// Sync flushes the buffer and commits the file to disk.
+func (w *bufferedFile) Sync() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	return w.f.Sync()
+}
+
+// Close flushes the buffer before closing the file. The file is closed even
+// if flushing fails, and the flush error is returned.
+func (w *bufferedFile) Close() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
+	err := w.w.Flush()
+	if closeErr := w.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
 type osFile struct {
-	f *os.File
+	f              *os.File
+	name           string
+	readDirEntries []DirEntry
 }
 
 func (f *osFile) Read(p []byte) (n int, err error) {
-	return f.f.Read(p)
+	n, err = f.f.Read(p)
+	if errors.Is(err, syscall.EISDIR) {
+		err = &FSError{Op: "read", Path: f.name, Err: ErrIsDir}
+	}
+	return n, err
+}
+
+func (f *osFile) Write(p []byte) (n int, err error) {
+	return f.f.Write(p)
+}
+
+func (f *osFile) WriteAt(p []byte, off int64) (n int, err error) {
+	return f.f.WriteAt(p, off)
+}
+
+func (f *osFile) Seek(offset int64, whence int) (int64, error) {
+	return f.f.Seek(offset, whence)
+}
+
+func (f *osFile) Stat() (os.FileInfo, error) {
+	return f.f.Stat()
 }
 
 func (f *osFile) Close() error {
@@ -88,13 +478,23 @@ func (f *osFile) Close() error {
 }
 
 func (f *osFile) ReadDir(n int) ([]DirEntry, error) {
-	fileInfos, err := f.f.Readdir(n)
-	if err != nil && os.IsNotExist(err) {
-		return nil, ErrNotFound
-	}
-	dirEntries := make([]DirEntry, len(fileInfos))
-	for i, info := range fileInfos {
-		dirEntries[i] = &dirEntry{name: info.Name(), isDir: info.IsDir()}
+	if f.readDirEntries == nil {
+		fileInfos, err := f.f.Readdir(-1)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, notFound("readdir", f.name)
+			}
+			if errors.Is(err, syscall.ENOTDIR) {
+				return nil, &FSError{Op: "readdir", Path: f.name, Err: ErrNotDir}
+			}
+			return nil, err
+		}
+		dirEntries := make([]DirEntry, len(fileInfos))
+		for i, info := range fileInfos {
+			dirEntries[i] = newDirEntry(info)
+		}
+		sortDirEntries(dirEntries)
+		f.readDirEntries = dirEntries
 	}
-	return dirEntries, err
+	return nextDirEntries(&f.readDirEntries, n)
 }
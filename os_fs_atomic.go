@@ -0,0 +1,117 @@
+package simplefs
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// WithAtomicCreate makes Create and CreateMode write to a temporary file in
+// the same directory, which is renamed into place when the writer is closed.
+// Readers see either the old contents of the file or the complete new ones,
+// never a partial write, and a crash before Close leaves the old file intact.
+// If writing or closing fails, the temporary file is removed. The temporary
+// file is named after the file with a ".tmp" suffix and a random number, and
+// is visible in ReadDir until Close. Append writes in place as usual.
+func WithAtomicCreate() OsOption {
+	return func(fs *osFs) {
+		fs.atomicCreate = true
+	}
+}
+
+// WithDirSync makes Rename, and Close of a writer returned by Create or
+// CreateMode with WithAtomicCreate, sync the directories involved, so that
+// the rename survives a crash on file systems that only persist it with the
+// directory. It costs an extra open and fsync per rename. On platforms or
+// file systems that cannot sync directories, the sync is skipped.
+func WithDirSync() OsOption {
+	return func(fs *osFs) {
+		fs.dirSync = true
+	}
+}
+
+// createAtomic creates a temporary file for the named file, and returns a
+// writer that renames it into place on Close. If chmod is false, the mode of
+// an existing file is kept, like Create does when truncating.
+func (fs *osFs) createAtomic(name string, perm os.FileMode, chmod bool) (io.WriteCloser, error) {
+	p, err := fs.path("create", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0666); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(p); err == nil {
+		if info.IsDir() {
+			return nil, &FSError{Op: "create", Path: name, Err: ErrIsDir}
+		}
+		if !chmod {
+			perm, chmod = info.Mode().Perm(), true
+		}
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if fs.directSync {
+		flag |= os.O_SYNC
+	}
+	var f *os.File
+	var tmp string
+	for i := 0; ; i++ {
+		tmp = p + ".tmp" + strconv.FormatUint(uint64(rand.Uint32()), 10)
+		if f, err = os.OpenFile(tmp, flag, perm); err == nil || !os.IsExist(err) || i == 100 {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if chmod {
+		if err := f.Chmod(perm); err != nil {
+			_ = f.Close()
+			_ = os.Remove(tmp)
+			return nil, err
+		}
+	}
+	return &atomicFile{w: fs.writer(f), tmp: tmp, path: p, dirSync: fs.dirSync}, nil
+}
+
+// atomicFile is a writer returned by an FS configured with WithAtomicCreate.
+// It writes to the temporary file tmp, which is renamed to path on Close.
+type atomicFile struct {
+	w       io.WriteCloser
+	tmp     string
+	path    string
+	dirSync bool
+	closed  bool
+}
+
+func (w *atomicFile) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// Sync commits the temporary file to disk.
+func (w *atomicFile) Sync() error {
+	return Sync(w.w)
+}
+
+// Close closes the temporary file and renames it into place, syncing the
+// directory if the FS was configured with WithDirSync.
+func (w *atomicFile) Close() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
+	if err := w.w.Close(); err != nil {
+		_ = os.Remove(w.tmp)
+		return err
+	}
+	if err := os.Rename(w.tmp, w.path); err != nil {
+		_ = os.Remove(w.tmp)
+		return err
+	}
+	if w.dirSync {
+		return syncDir(filepath.Dir(w.path))
+	}
+	return nil
+}
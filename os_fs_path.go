@@ -0,0 +1,86 @@
+package simplefs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrPathEscape is returned by OsFS when a name refers to a path outside its
+// directory, either with ".." elements or through a symbolic link, and by
+// MemFS when a name starts with "..".
+var ErrPathEscape = fmt.Errorf("path escapes the root directory")
+
+// path returns the OS path of name, or an *FSError wrapping ErrPathEscape if
+// it is outside fs.dir. Names are always relative to fs.dir, so a leading
+// slash is ignored. Symbolic links are resolved to check where the path
+// really leads, except for a link in the last element when followLast is
+// false, as for Remove and Readlink, which act on the link itself.
+//
+// The check is made before the path is used, so a link that is swapped in
+// by another process in between is not detected.
+func (fs *osFs) path(op, name string, followLast bool) (string, error) {
+	root := filepath.Clean(fs.dir)
+	p := filepath.Join(root, filepath.FromSlash(name))
+	escape := &FSError{Op: op, Path: name, Err: ErrPathEscape}
+	if !isWithin(root, p) {
+		return "", escape
+	}
+	resolvedRoot, err := resolveExisting(root, 0)
+	if err != nil {
+		return "", err
+	}
+	var resolved string
+	if followLast || p == root {
+		resolved, err = resolveExisting(p, 0)
+	} else {
+		resolved, err = resolveExisting(filepath.Dir(p), 0)
+		resolved = filepath.Join(resolved, filepath.Base(p))
+	}
+	if err != nil {
+		return "", &FSError{Op: op, Path: name, Err: err}
+	}
+	if !isWithin(resolvedRoot, resolved) {
+		return "", escape
+	}
+	return p, nil
+}
+
+// resolveExisting returns p with all symbolic links resolved. Unlike
+// filepath.EvalSymlinks, p does not need to exist: the elements that do not
+// exist yet are kept as they are, and dangling links are resolved to where
+// they point.
+func resolveExisting(p string, hops int) (string, error) {
+	var tail string
+	for q := p; ; {
+		if r, err := filepath.EvalSymlinks(q); err == nil {
+			return filepath.Join(r, tail), nil
+		}
+		if target, err := os.Readlink(q); err == nil {
+			if hops++; hops > maxLinkHops {
+				return "", ErrTooManyLinks
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(q), target)
+			}
+			r, err := resolveExisting(target, hops)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(r, tail), nil
+		}
+		parent := filepath.Dir(q)
+		if parent == q {
+			return p, nil
+		}
+		tail = filepath.Join(filepath.Base(q), tail)
+		q = parent
+	}
+}
+
+// isWithin reports whether p is dir or a path inside it. Both must be clean.
+func isWithin(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
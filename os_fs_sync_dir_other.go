@@ -0,0 +1,8 @@
+//go:build !unix
+
+package simplefs
+
+// syncDir does nothing, since directories cannot be synced on this platform.
+func syncDir(dir string) error {
+	return nil
+}
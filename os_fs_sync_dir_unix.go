@@ -0,0 +1,27 @@
+//go:build unix
+
+package simplefs
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// syncDir commits the entries of dir to disk with fsync(2). Some file
+// systems do not support syncing directories and fail with EINVAL or
+// ENOTSUP, which is not reported as an error.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTSUP) {
+		err = nil
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -1,17 +1,385 @@
 package simplefs
 
 import (
-	"fmt"
+	"errors"
+	"io"
 	"os"
-	"path"
+	"path/filepath"
+	"strings"
 	"testing"
-	"time"
 )
 
 func TestOsFileSystem(t *testing.T) {
-	dir := path.Join(os.TempDir(), fmt.Sprintf("simplefs_%d", time.Now().UnixNano()))
-	defer func() { _ = os.RemoveAll(dir) }()
+	dir := t.TempDir()
 	if msg := RunFileSystemTest(OsFS(dir)); msg != "" {
 		t.Fatal(msg)
 	}
 }
+
+func TestOsFileReadDirPaging(t *testing.T) {
+	dir := t.TempDir()
+	fs := OsFS(dir)
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, name := range names {
+		w, err := fs.Create("dir/" + name)
+		if err != nil {
+			t.Fatalf("Create(%s) error: %v", name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}
+
+	f, err := fs.Open("dir")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var got []string
+	var eofCount int
+	for i := 0; i < 10; i++ {
+		entries, err := f.ReadDir(2)
+		for _, entry := range entries {
+			got = append(got, entry.Name())
+		}
+		if err == io.EOF {
+			eofCount++
+			if len(entries) != 0 {
+				t.Fatalf("ReadDir(2) returned %d entries with io.EOF", len(entries))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadDir(2) error: %v", err)
+		}
+	}
+	if eofCount != 1 {
+		t.Fatalf("Got %d terminal io.EOF, want 1", eofCount)
+	}
+	if strings.Join(got, ",") != strings.Join(names, ",") {
+		t.Fatalf("Got entries %v, want %v", got, names)
+	}
+
+	// Once drained, further paged reads keep returning io.EOF while reading
+	// all remaining entries returns nothing and no error.
+	if _, err := f.ReadDir(2); err != io.EOF {
+		t.Fatalf("ReadDir(2) after drain returned %v, want io.EOF", err)
+	}
+	if entries, err := f.ReadDir(-1); err != nil || len(entries) != 0 {
+		t.Fatalf("ReadDir(-1) after drain returned %v, %v", entries, err)
+	}
+}
+
+func TestOsFSCreateMode(t *testing.T) {
+	dir := t.TempDir()
+	fs := OsFS(dir)
+	for name, mode := range map[string]os.FileMode{"secret": 0600, "bin/script.sh": 0755} {
+		w, err := CreateMode(fs, name, mode)
+		if err != nil {
+			t.Fatalf("CreateMode(%s) error: %v", name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+		info, err := Stat(fs, name)
+		if err != nil {
+			t.Fatalf("Stat(%s) error: %v", name, err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Fatalf("Stat(%s).Mode() returned %v, want %v", name, info.Mode().Perm(), mode)
+		}
+	}
+}
+
+func TestOsFSSymlink(t *testing.T) {
+	dir := t.TempDir()
+	fs := OsFS(dir)
+	if err := WriteString(fs, "dir/file", "contents"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	symlinker := fs.(Symlinker)
+	if err := symlinker.Symlink("dir/file", "link"); err != nil {
+		t.Fatalf("Symlink() error: %v", err)
+	}
+	if target, err := symlinker.Readlink("link"); err != nil || target != "dir/file" {
+		t.Fatalf("Readlink() returned %q, %v", target, err)
+	}
+	if b, err := ReadFile(fs, "link"); err != nil || string(b) != "contents" {
+		t.Fatalf("ReadFile(link) returned %q, %v", b, err)
+	}
+	if _, err := symlinker.Readlink("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Readlink() on missing file returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestOsFSDirectSync(t *testing.T) {
+	dir := t.TempDir()
+	fs := OsFS(dir, WithDirectSync())
+	if err := WriteString(fs, "dir/file", "durable"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	w, err := fs.Append("dir/file")
+	if err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+	if _, err := w.Write([]byte(" append")); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if b, err := ReadFile(fs, "dir/file"); err != nil || string(b) != "durable append" {
+		t.Fatalf("ReadFile() returned %q, %v", b, err)
+	}
+	if msg := RunFileSystemTest(OsFS(filepath.Join(dir, "conformance"), WithDirectSync())); msg != "" {
+		t.Fatal(msg)
+	}
+}
+
+func TestOsFSReadDirSizes(t *testing.T) {
+	fs := OsFS(t.TempDir())
+	sizes := map[string]int{"empty": 0, "small": 5, "large": 100000}
+	for name, size := range sizes {
+		if err := WriteFile(fs, "dir/"+name, make([]byte, size)); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+	}
+	entries, err := fs.ReadDir("dir")
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if len(entries) != len(sizes) {
+		t.Fatalf("ReadDir() returned %d entries, want %d", len(entries), len(sizes))
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			t.Fatalf("%s: Info() error: %v", entry.Name(), err)
+		}
+		if info.Size() != int64(sizes[entry.Name()]) {
+			t.Fatalf("%s: Info().Size() returned %d, want %d", entry.Name(), info.Size(), sizes[entry.Name()])
+		}
+	}
+}
+
+func TestSyncer(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		for _, open := range []func(string) (io.WriteCloser, error){fs.Create, fs.Append} {
+			w, err := open("file")
+			if err != nil {
+				t.Fatalf("%s: error opening writer: %v", name, err)
+			}
+			if _, ok := w.(Syncer); !ok {
+				t.Fatalf("%s: %T does not implement Syncer", name, w)
+			}
+			if _, err := w.Write([]byte("data")); err != nil {
+				t.Fatalf("%s: Write() error: %v", name, err)
+			}
+			if err := Sync(w); err != nil {
+				t.Fatalf("%s: Sync() error: %v", name, err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("%s: Close() error: %v", name, err)
+			}
+		}
+	}
+}
+
+func TestOsFSPathEscape(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	fs := OsFS(root)
+	if err := WriteString(OsFS(base), "secret", "secret"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if err := WriteString(fs, "file", "file"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if err := os.Symlink(base, filepath.Join(root, "escape")); err != nil {
+		t.Fatalf("Symlink() error: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(base, "created"), filepath.Join(root, "dangling")); err != nil {
+		t.Fatalf("Symlink() error: %v", err)
+	}
+	if err := fs.(Symlinker).Symlink("file", "inside"); err != nil {
+		t.Fatalf("Symlink() error: %v", err)
+	}
+
+	for _, name := range []string{"../secret", "dir/../../secret", "escape/secret", "escape", "dangling"} {
+		if _, err := fs.Open(name); !errors.Is(err, ErrPathEscape) {
+			t.Fatalf("Open(%s) returned %v, want ErrPathEscape", name, err)
+		}
+		if _, err := fs.Create(name); !errors.Is(err, ErrPathEscape) {
+			t.Fatalf("Create(%s) returned %v, want ErrPathEscape", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(base, "created")); !os.IsNotExist(err) {
+		t.Fatalf("Create through a dangling link wrote outside the root: %v", err)
+	}
+	if err := RemoveAll(fs, ".."); !errors.Is(err, ErrPathEscape) {
+		t.Fatalf("RemoveAll(..) returned %v, want ErrPathEscape", err)
+	}
+
+	if err := WriteString(fs, "/abs", "abs"); err != nil {
+		t.Fatalf("WriteString(/abs) error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "abs")); err != nil {
+		t.Fatalf("WriteString(/abs) did not write inside the root: %v", err)
+	}
+	if b, err := ReadFile(fs, "inside"); err != nil || string(b) != "file" {
+		t.Fatalf("ReadFile(inside) returned %q, %v, want %q", b, err, "file")
+	}
+	if err := Remove(fs, "escape"); err != nil {
+		t.Fatalf("Remove() of a link pointing outside returned %v, want nil", err)
+	}
+	if b, err := ReadFile(OsFS(base), "secret"); err != nil || string(b) != "secret" {
+		t.Fatalf("ReadFile(secret) returned %q, %v", b, err)
+	}
+}
+
+func TestOsFSWriteBuffer(t *testing.T) {
+	dir := t.TempDir()
+	fs := OsFS(dir, WithWriteBuffer(16))
+	w, err := fs.Create("file")
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write([]byte("abc")); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "file")); len(b) >= 30 {
+		t.Fatalf("File has %d bytes before Close(), want the last writes still buffered", len(b))
+	}
+	if err := Sync(w); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "file")); len(b) != 30 {
+		t.Fatalf("File has %d bytes after Sync(), want 30", len(b))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	w, _ = fs.Append("file")
+	_, _ = w.Write([]byte("!"))
+	_ = w.(*bufferedFile).f.Close()
+	if err := w.Close(); err == nil {
+		t.Fatalf("Close() returned nil error when flushing failed")
+	}
+
+	if msg := RunFileSystemTest(OsFS(filepath.Join(dir, "conformance"), WithWriteBuffer(4))); msg != "" {
+		t.Fatal(msg)
+	}
+}
+
+func BenchmarkOsFSSmallWrites(b *testing.B) {
+	record := []byte("a small log record\n")
+	for name, opts := range map[string][]OsOption{"Unbuffered": nil, "Buffered": {WithWriteBuffer(64 << 10)}} {
+		b.Run(name, func(b *testing.B) {
+			fs := OsFS(b.TempDir(), opts...)
+			w, err := fs.Create("log")
+			if err != nil {
+				b.Fatalf("Create() error: %v", err)
+			}
+			b.SetBytes(int64(len(record)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := w.Write(record); err != nil {
+					b.Fatalf("Write() error: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				b.Fatalf("Close() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestOsFSSeparators(t *testing.T) {
+	dir := t.TempDir()
+	fs := OsFS(dir)
+	if err := WriteString(fs, "a/b/c.txt", "c"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	// The file is stored under the OS path, whatever the separator.
+	if b, err := os.ReadFile(filepath.Join(dir, "a", "b", "c.txt")); err != nil || string(b) != "c" {
+		t.Fatalf("ReadFile() on the OS path returned %q, %v", b, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a", "b", "d.txt"), []byte("d"), 0666); err != nil {
+		t.Fatalf("os.WriteFile() error: %v", err)
+	}
+	if b, err := ReadFile(fs, "a/b/d.txt"); err != nil || string(b) != "d" {
+		t.Fatalf("ReadFile() returned %q, %v", b, err)
+	}
+
+	entries, err := fs.ReadDir("a/b")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "c.txt" || entries[1].Name() != "d.txt" {
+		t.Fatalf("ReadDir() returned %v, %v", entries, err)
+	}
+	dirs, err := fs.(interface {
+		ListDirs(dir string) ([]string, error)
+	}).ListDirs(".")
+	if err != nil || strings.Join(dirs, ",") != "a,a/b" {
+		t.Fatalf("ListDirs() returned %v, %v, want slash-separated paths", dirs, err)
+	}
+
+	links := fs.(Symlinker)
+	if err := links.Symlink("b/c.txt", "a/link"); err != nil {
+		t.Fatalf("Symlink() error: %v", err)
+	}
+	if target, err := links.Readlink("a/link"); err != nil || target != "b/c.txt" {
+		t.Fatalf("Readlink() returned %q, %v, want %q", target, err, "b/c.txt")
+	}
+	if b, err := ReadFile(fs, "a/link"); err != nil || string(b) != "c" {
+		t.Fatalf("ReadFile() through link returned %q, %v", b, err)
+	}
+}
+
+func TestOsFSAtomicCreate(t *testing.T) {
+	dir := t.TempDir()
+	fs := OsFS(dir, WithAtomicCreate(), WithDirSync())
+	if err := WriteString(fs, "dir/file", "old"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "dir", "file"), 0600); err != nil {
+		t.Fatalf("Chmod() error: %v", err)
+	}
+
+	w, err := fs.Create("dir/file")
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	_, _ = w.Write([]byte("new"))
+	if b, _ := ReadFile(fs, "dir/file"); string(b) != "old" {
+		t.Fatalf("File contains %q before Close(), want %q", b, "old")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if b, _ := ReadFile(fs, "dir/file"); string(b) != "new" {
+		t.Fatalf("File contains %q after Close(), want %q", b, "new")
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "dir", "file")); info.Mode().Perm() != 0600 {
+		t.Fatalf("Create() changed the mode to %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+	if err := w.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Second Close() returned %v, want os.ErrClosed", err)
+	}
+	if entries, _ := fs.ReadDir("dir"); len(entries) != 1 {
+		t.Fatalf("ReadDir() returned %v, want only the file", entries)
+	}
+
+	if _, err := fs.Create("dir"); !errors.Is(err, ErrIsDir) {
+		t.Fatalf("Create() on a directory returned %v, want ErrIsDir", err)
+	}
+	if err := fs.(Renamer).Rename("dir/file", "other/file"); err != nil {
+		t.Fatalf("Rename() error: %v", err)
+	}
+
+	if msg := RunFileSystemTest(OsFS(filepath.Join(dir, "conformance"), WithAtomicCreate(), WithDirSync(), WithWriteBuffer(4))); msg != "" {
+		t.Fatal(msg)
+	}
+}
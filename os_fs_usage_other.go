@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package simplefs
+
+import "fmt"
+
+// Usage implements StatFS. statfs(2) is not available on this platform, so it
+// returns an error wrapping ErrNotImplemented.
+func (fs *osFs) Usage() (used, capacity int64, err error) {
+	return 0, 0, fmt.Errorf("cannot report usage of '%s'. statfs is not supported: %w", fs.dir, ErrNotImplemented)
+}
@@ -0,0 +1,22 @@
+//go:build linux || darwin
+
+package simplefs
+
+import "syscall"
+
+// Usage implements StatFS with statfs(2). It reports the usage of the whole
+// file system containing the directory, not just of the files beneath it.
+func (fs *osFs) Usage() (used, capacity int64, err error) {
+	p, err := fs.path("statfs", ".", true)
+	if err != nil {
+		return 0, 0, err
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(p, &st); err != nil {
+		return 0, 0, &FSError{Op: "statfs", Path: ".", Err: err}
+	}
+	bsize := int64(st.Bsize)
+	capacity = int64(st.Blocks) * bsize
+	used = capacity - int64(st.Bfree)*bsize
+	return used, capacity, nil
+}
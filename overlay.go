@@ -0,0 +1,406 @@
+package simplefs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// whiteoutPrefix is the prefix of the files an Overlay writes to its upper
+// layer to hide paths of the lower layer, following the AUFS convention.
+const whiteoutPrefix = ".wh."
+
+// opaqueMarker is the name of the file that, in a directory of an Overlay's
+// upper layer, hides everything in the same directory of the lower layer.
+const opaqueMarker = whiteoutPrefix + whiteoutPrefix + ".opq"
+
+// Overlay returns an FS that presents upper on top of lower, like a union
+// mount. Paths are read from upper if they exist there and from lower
+// otherwise, and directory listings merge both layers. All writes go to
+// upper and lower is never modified; appending to a file that only exists in
+// lower first copies it up.
+//
+// Removing or renaming a path that exists in lower records a whiteout in
+// upper: an empty file named ".wh.<name>" next to the path, which hides it in
+// lower. Writing a file below a whited-out directory turns the whiteout into
+// an opaque marker, a ".wh..wh..opq" file in the directory, so that the
+// directory reappears without its old lower contents. Whiteouts and opaque
+// markers are never listed. Remove, RemoveAll and Rename require upper to
+// implement Remover.
+func Overlay(lower, upper FS) FS {
+	return &overlayFS{lower: lower, upper: upper}
+}
+
+type overlayFS struct {
+	lower FS
+	upper FS
+}
+
+// Unwrap returns the upper layer, which receives all writes.
+func (fs *overlayFS) Unwrap() FS {
+	return fs.upper
+}
+
+func (fs *overlayFS) Open(name string) (File, error) {
+	f, err := fs.upper.Open(name)
+	if errors.Is(err, ErrNotFound) {
+		f, err = fs.openLower(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isDirFile(f) {
+		return f, nil
+	}
+	info, err := f.(statFile).Stat()
+	_ = f.Close()
+	if err != nil {
+		return nil, err
+	}
+	return &overlayDir{fs: fs, name: name, info: info}, nil
+}
+
+// openLower opens the named path in the lower layer unless it is hidden.
+func (fs *overlayFS) openLower(name string) (File, error) {
+	hidden, err := fs.hidden(name)
+	if err != nil {
+		return nil, err
+	}
+	if hidden {
+		return nil, notFound("open", name)
+	}
+	return fs.lower.Open(name)
+}
+
+func (fs *overlayFS) Stat(name string) (os.FileInfo, error) {
+	info, err := Stat(fs.upper, name)
+	if !errors.Is(err, ErrNotFound) {
+		return info, err
+	}
+	hidden, err := fs.hidden(name)
+	if err != nil {
+		return nil, err
+	}
+	if hidden {
+		return nil, notFound("stat", name)
+	}
+	return Stat(fs.lower, name)
+}
+
+func (fs *overlayFS) Lstat(name string) (os.FileInfo, error) {
+	info, err := Lstat(fs.upper, name)
+	if !errors.Is(err, ErrNotFound) {
+		return info, err
+	}
+	hidden, err := fs.hidden(name)
+	if err != nil {
+		return nil, err
+	}
+	if hidden {
+		return nil, notFound("lstat", name)
+	}
+	return Lstat(fs.lower, name)
+}
+
+func (fs *overlayFS) ReadDir(name string) ([]DirEntry, error) {
+	upperEntries, upperErr := fs.upper.ReadDir(name)
+	if upperErr != nil && !errors.Is(upperErr, ErrNotFound) {
+		return nil, upperErr
+	}
+	var lowerEntries []DirEntry
+	lowerErr := ErrNotFound
+	hidden, err := fs.hidden(name)
+	if err != nil {
+		return nil, err
+	}
+	if !hidden {
+		lowerEntries, lowerErr = fs.lower.ReadDir(name)
+		if lowerErr != nil && !errors.Is(lowerErr, ErrNotFound) {
+			return nil, lowerErr
+		}
+	}
+	if upperErr != nil && lowerErr != nil {
+		return nil, notFound("readdir", name)
+	}
+
+	entries := make([]DirEntry, 0, len(upperEntries)+len(lowerEntries))
+	shadowed := map[string]bool{}
+	opaque := false
+	for _, entry := range upperEntries {
+		switch {
+		case entry.Name() == opaqueMarker:
+			opaque = true
+		case strings.HasPrefix(entry.Name(), whiteoutPrefix):
+			shadowed[strings.TrimPrefix(entry.Name(), whiteoutPrefix)] = true
+		default:
+			shadowed[entry.Name()] = true
+			entries = append(entries, entry)
+		}
+	}
+	if !opaque {
+		for _, entry := range lowerEntries {
+			if !shadowed[entry.Name()] {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	sortDirEntries(entries)
+	return entries, nil
+}
+
+func (fs *overlayFS) Create(name string) (io.WriteCloser, error) {
+	if err := fs.unhide(name); err != nil {
+		return nil, err
+	}
+	return fs.upper.Create(name)
+}
+
+func (fs *overlayFS) Append(name string) (io.WriteCloser, error) {
+	inUpper, err := exists(fs.upper, name)
+	if err != nil {
+		return nil, err
+	}
+	if !inUpper {
+		if err := fs.copyUp(name); err != nil {
+			return nil, err
+		}
+	}
+	return fs.upper.Append(name)
+}
+
+// copyUp copies the named file from the lower layer to the upper layer, if
+// it is visible in the lower layer.
+func (fs *overlayFS) copyUp(name string) error {
+	f, err := fs.openLower(name)
+	if errors.Is(err, ErrNotFound) {
+		return fs.unhide(name)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	if err := fs.unhide(name); err != nil {
+		return err
+	}
+	w, err := fs.upper.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (fs *overlayFS) Remove(name string) error {
+	if _, ok := fs.upper.(Remover); !ok {
+		return fmt.Errorf("cannot remove '%s'. %T does not implement Remover: %w", name, fs.upper, ErrNotImplemented)
+	}
+	f, err := fs.Open(name)
+	if err != nil {
+		return err
+	}
+	isDir := isDirFile(f)
+	_ = f.Close()
+	if isDir {
+		entries, err := fs.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("cannot remove '%s'. Directory is not empty", name)
+		}
+	}
+	return fs.removeAll(name)
+}
+
+func (fs *overlayFS) RemoveAll(name string) error {
+	if _, ok := fs.upper.(Remover); !ok {
+		return fmt.Errorf("cannot remove '%s'. %T does not implement Remover: %w", name, fs.upper, ErrNotImplemented)
+	}
+	if path.Clean(name) == "." {
+		entries, err := fs.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := fs.removeAll(entry.Name()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fs.removeAll(name)
+}
+
+// removeAll removes name from the upper layer and whites it out if it is
+// visible in the lower layer.
+func (fs *overlayFS) removeAll(name string) error {
+	if err := RemoveAll(fs.upper, name); err != nil {
+		return err
+	}
+	f, err := fs.openLower(name)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_ = f.Close()
+	return WriteFile(fs.upper, whiteoutPath(name), nil)
+}
+
+// Rename renames oldName to newName. A file that only exists in the upper
+// layer is renamed there if the upper layer implements Renamer. Otherwise
+// the file, or every file in the directory tree, is copied to newName in the
+// upper layer and oldName is then removed, which whites it out in the lower
+// layer. Empty directories are not carried over by a copy.
+func (fs *overlayFS) Rename(oldName, newName string) error {
+	if _, ok := fs.upper.(Remover); !ok {
+		return fmt.Errorf("cannot rename '%s'. %T does not implement Remover: %w", oldName, fs.upper, ErrNotImplemented)
+	}
+	f, err := fs.Open(oldName)
+	if err != nil {
+		return err
+	}
+	isDir := isDirFile(f)
+	_ = f.Close()
+
+	if !isDir {
+		if r, ok := fs.upper.(Renamer); ok {
+			inUpper, err := exists(fs.upper, oldName)
+			if err != nil {
+				return err
+			}
+			if inUpper {
+				if err := fs.unhide(newName); err != nil {
+					return err
+				}
+				if err := r.Rename(oldName, newName); err != nil {
+					return err
+				}
+				return fs.removeAll(oldName)
+			}
+		}
+		if err := CopyFile(fs, newName, fs, oldName); err != nil {
+			return err
+		}
+		return fs.removeAll(oldName)
+	}
+
+	prefix := path.Clean(oldName) + "/"
+	err = WalkFiles(fs, oldName, func(name string) error {
+		return CopyFile(fs, path.Join(newName, strings.TrimPrefix(name, prefix)), fs, name)
+	})
+	if err != nil {
+		return err
+	}
+	return fs.removeAll(oldName)
+}
+
+// hidden reports whether the named path of the lower layer is hidden by a
+// whiteout of the path or one of its ancestors, or by an opaque marker in
+// one of its ancestors.
+func (fs *overlayFS) hidden(name string) (bool, error) {
+	name = path.Clean(name)
+	if name == "." {
+		return false, nil
+	}
+	for _, p := range append([]string{name}, Ancestors(name)...) {
+		if p != "." {
+			if ok, err := exists(fs.upper, whiteoutPath(p)); ok || err != nil {
+				return ok, err
+			}
+		}
+		if p != name {
+			if ok, err := exists(fs.upper, path.Join(p, opaqueMarker)); ok || err != nil {
+				return ok, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// unhide removes the whiteouts that would hide name once it is written to the
+// upper layer. A whited-out ancestor is replaced by an opaque marker, so that
+// the rest of its lower contents stay hidden.
+func (fs *overlayFS) unhide(name string) error {
+	ancestors := Ancestors(name)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		dir := ancestors[i]
+		if dir == "." {
+			continue
+		}
+		ok, err := exists(fs.upper, whiteoutPath(dir))
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := Remove(fs.upper, whiteoutPath(dir)); err != nil {
+				return err
+			}
+			if err := WriteFile(fs.upper, path.Join(dir, opaqueMarker), nil); err != nil {
+				return err
+			}
+		}
+	}
+	ok, err := exists(fs.upper, whiteoutPath(name))
+	if err != nil || !ok {
+		return err
+	}
+	return Remove(fs.upper, whiteoutPath(name))
+}
+
+// whiteoutPath returns the path of the whiteout that hides name.
+func whiteoutPath(name string) string {
+	name = path.Clean(name)
+	return path.Join(path.Dir(name), whiteoutPrefix+path.Base(name))
+}
+
+// exists reports whether the named path can be opened in fs.
+func exists(fs FS, name string) (bool, error) {
+	f, err := fs.Open(name)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, f.Close()
+}
+
+// overlayDir is a directory opened from an Overlay. Its entries are the merged
+// listing of both layers.
+type overlayDir struct {
+	fs             *overlayFS
+	name           string
+	info           os.FileInfo
+	readDirEntries []DirEntry
+}
+
+func (dir *overlayDir) Stat() (os.FileInfo, error) {
+	return dir.info, nil
+}
+
+func (dir *overlayDir) Read(p []byte) (n int, err error) {
+	return 0, &FSError{Op: "read", Path: dir.name, Err: ErrIsDir}
+}
+
+func (dir *overlayDir) Close() error {
+	return nil
+}
+
+func (dir *overlayDir) ReadDir(n int) ([]DirEntry, error) {
+	if dir.readDirEntries == nil {
+		entries, err := dir.fs.ReadDir(dir.name)
+		if err != nil {
+			return nil, err
+		}
+		dir.readDirEntries = entries
+	}
+	return nextDirEntries(&dir.readDirEntries, n)
+}
@@ -0,0 +1,158 @@
+package simplefs
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	lower := NewMemFSFromStrings(map[string]string{
+		"a":         "lower a",
+		"b":         "lower b",
+		"dir/c":     "lower c",
+		"dir/sub/d": "lower d",
+	})
+	upper := NewMemFSFromStrings(map[string]string{
+		"b": "upper b",
+		"e": "upper e",
+	})
+	fs := Overlay(lower, upper)
+
+	list := func(dir string) string {
+		entries, err := fs.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("ReadDir(%s) error: %v", dir, err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return strings.Join(names, ",")
+	}
+	assertContents := func(name, want string) {
+		t.Helper()
+		if b, err := ReadFile(fs, name); err != nil || string(b) != want {
+			t.Fatalf("ReadFile(%s) returned %q, %v, want %q", name, b, err, want)
+		}
+	}
+	assertNotFound := func(name string) {
+		t.Helper()
+		if _, err := fs.Open(name); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Open(%s) returned %v, want ErrNotFound", name, err)
+		}
+	}
+
+	assertContents("a", "lower a")
+	assertContents("b", "upper b")
+	if got := list("."); got != "a,b,dir,e" {
+		t.Fatalf("ReadDir(.) returned %s", got)
+	}
+
+	if err := Rename(fs, "a", "dir/renamed"); err != nil {
+		t.Fatalf("Rename() error: %v", err)
+	}
+	assertNotFound("a")
+	assertContents("dir/renamed", "lower a")
+	if got := list("."); got != "b,dir,e" {
+		t.Fatalf("ReadDir(.) after rename returned %s", got)
+	}
+	if got := list("dir"); got != "c,renamed,sub" {
+		t.Fatalf("ReadDir(dir) after rename returned %s", got)
+	}
+
+	if err := Remove(fs, "dir/c"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	assertNotFound("dir/c")
+
+	if err := RemoveAll(fs, "dir"); err != nil {
+		t.Fatalf("RemoveAll() error: %v", err)
+	}
+	assertNotFound("dir/sub/d")
+	if err := WriteString(fs, "dir/new", "new"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if got := list("dir"); got != "new" {
+		t.Fatalf("ReadDir(dir) after recreate returned %s", got)
+	}
+	assertNotFound("dir/sub/d")
+
+	if err := Rename(fs, "e", "f"); err != nil {
+		t.Fatalf("Rename() error: %v", err)
+	}
+	assertNotFound("e")
+	assertContents("f", "upper e")
+
+	for name, want := range map[string]string{"a": "lower a", "dir/c": "lower c", "dir/sub/d": "lower d"} {
+		if b, err := ReadFile(lower, name); err != nil || string(b) != want {
+			t.Fatalf("lower layer was modified: ReadFile(%s) returned %q, %v", name, b, err)
+		}
+	}
+}
+
+func TestOverlayAppendCopiesUp(t *testing.T) {
+	lower := NewMemFSFromStrings(map[string]string{"log": "one\n"})
+	upper := &MemFS{}
+	fs := Overlay(lower, upper)
+
+	w, err := fs.Append("log")
+	if err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+	if _, err := w.Write([]byte("two\n")); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if b, err := ReadFile(fs, "log"); err != nil || string(b) != "one\ntwo\n" {
+		t.Fatalf("ReadFile() returned %q, %v", b, err)
+	}
+	if b, _ := ReadFile(lower, "log"); string(b) != "one\n" {
+		t.Fatalf("lower layer was modified: %q", b)
+	}
+}
+
+func TestOverlayLstat(t *testing.T) {
+	lower := NewMemFSFromStrings(map[string]string{"only-lower": "x", "removed": "y"})
+	if err := lower.Symlink("only-lower", "link"); err != nil {
+		t.Fatalf("Symlink() error: %v", err)
+	}
+	upper := NewMemFSFromStrings(map[string]string{"only-upper": "zz"})
+	fs := Overlay(lower, upper)
+
+	if info, err := Lstat(fs, "only-lower"); err != nil || info.Size() != 1 {
+		t.Fatalf("Lstat(only-lower) returned %v, %v, want the lower file", info, err)
+	}
+	if info, err := Lstat(fs, "only-upper"); err != nil || info.Size() != 2 {
+		t.Fatalf("Lstat(only-upper) returned %v, %v, want the upper file", info, err)
+	}
+	if info, err := Lstat(fs, "link"); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("Lstat(link) returned %v, %v, want the link", info, err)
+	}
+	if err := Remove(fs, "removed"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	if _, err := Lstat(fs, "removed"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Lstat(removed) returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestOverlayNotFoundErrors(t *testing.T) {
+	lower := NewMemFSFromStrings(map[string]string{"dir/file": "x"})
+	fs := Overlay(lower, &MemFS{})
+	if err := RemoveAll(fs, "dir"); err != nil {
+		t.Fatalf("RemoveAll() error: %v", err)
+	}
+	_, openErr := fs.Open("dir/file")
+	_, statErr := Stat(fs, "dir/file")
+	_, readDirErr := fs.ReadDir("dir")
+	for op, err := range map[string]error{"open": openErr, "stat": statErr, "readdir": readDirErr} {
+		var fsErr *FSError
+		if !errors.As(err, &fsErr) || fsErr.Op != op || !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s of a hidden path returned %#v, want an *FSError wrapping ErrNotFound", op, err)
+		}
+	}
+}
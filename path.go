@@ -0,0 +1,47 @@
+package simplefs
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// CleanPath returns the canonical form of name that all FS implementations
+// use: it is cleaned with path.Clean, and leading slashes are removed, since
+// names are relative to the root of the FS. The root is returned as ".". For
+// example, "a//b", "a/./b", "/a/b/" and "a/c/../b" all clean to "a/b". A
+// leading ".." is kept, so the result may refer outside the FS: "../a" and
+// "a/../../b" clean to "../a" and "../b". Use CleanLocalPath to reject such
+// names.
+func CleanPath(name string) string {
+	name = strings.TrimLeft(path.Clean(name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// CleanLocalPath is like CleanPath, but returns an error wrapping
+// ErrPathEscape if the cleaned name refers outside the root of the FS.
+func CleanLocalPath(name string) (string, error) {
+	clean := CleanPath(name)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("cannot use '%s'. %w", name, ErrPathEscape)
+	}
+	return clean, nil
+}
+
+// Ancestors returns the paths of the directories containing name, from its
+// immediate parent up to and including the root, which is returned as ".".
+// For example, Ancestors("a/b/c") returns ["a/b", "a", "."]. The root itself
+// has no ancestors. Names are slash-separated and cleaned first, so leading
+// and trailing slashes are ignored.
+func Ancestors(name string) []string {
+	name = path.Clean(strings.Trim(name, "/"))
+	var ancestors []string
+	for name != "." {
+		name = path.Dir(name)
+		ancestors = append(ancestors, name)
+	}
+	return ancestors
+}
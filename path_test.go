@@ -0,0 +1,71 @@
+package simplefs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAncestors(t *testing.T) {
+	tests := map[string]string{
+		"a/b/c":    "a/b,a,.",
+		"a":        ".",
+		"/a/b/":    "a,.",
+		"a//b/./c": "a/b,a,.",
+		".":        "",
+		"":         "",
+	}
+	for name, want := range tests {
+		if got := strings.Join(Ancestors(name), ","); got != want {
+			t.Fatalf("Ancestors(%q) returned %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := map[string]string{
+		"a/b":       "a/b",
+		"a//b":      "a/b",
+		"a/./b":     "a/b",
+		"a/b/":      "a/b",
+		"/a/b":      "a/b",
+		"a/c/../b":  "a/b",
+		"":          ".",
+		".":         ".",
+		"/":         ".",
+		"./":        ".",
+		"../a":      "../a",
+		"/../a":     "a",
+		"a/../../b": "../b",
+		"..":        "..",
+		"..a":       "..a",
+	}
+	for name, want := range tests {
+		if got := CleanPath(name); got != want {
+			t.Fatalf("CleanPath(%q) returned %q, want %q", name, got, want)
+		}
+	}
+
+	for name, want := range tests {
+		got, err := CleanLocalPath(name)
+		if want == ".." || strings.HasPrefix(want, "../") {
+			if !errors.Is(err, ErrPathEscape) {
+				t.Fatalf("CleanLocalPath(%q) returned %q, %v, want ErrPathEscape", name, got, err)
+			}
+		} else if err != nil || got != want {
+			t.Fatalf("CleanLocalPath(%q) returned %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	// Names that clean to the same path refer to the same file.
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		w, _ := fs.Create("a//b/")
+		_, _ = w.Write([]byte("data"))
+		_ = w.Close()
+		for _, other := range []string{"a/b", "/a/./b", "a/c/../b"} {
+			if b, err := ReadFile(fs, other); err != nil || string(b) != "data" {
+				t.Fatalf("%s: ReadFile(%s) returned %q, %v", name, other, b, err)
+			}
+		}
+	}
+}
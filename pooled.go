@@ -0,0 +1,54 @@
+package simplefs
+
+import (
+	"bufio"
+	"fmt"
+	"sync"
+)
+
+// OpenPooled opens the named file for reading through a *bufio.Reader taken
+// from pool, and puts the reader back in the pool when the file is closed.
+// The pool's New function, if set, must return a *bufio.Reader; values of any
+// other type are ignored and a new reader is allocated instead.
+//
+// Only backends whose reads go to a system call benefit, which in this package
+// means OsFS and wrappers over it. Files from a MemFS are already served from
+// memory and are returned unwrapped, as are directories.
+func OpenPooled(fs FS, name string, pool *sync.Pool) (File, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.(*memFile); ok || isDirFile(f) {
+		return f, nil
+	}
+	r, _ := pool.Get().(*bufio.Reader)
+	if r == nil {
+		r = bufio.NewReader(f)
+	} else {
+		r.Reset(f)
+	}
+	return &pooledFile{File: f, r: r, pool: pool}, nil
+}
+
+type pooledFile struct {
+	File
+	r    *bufio.Reader
+	pool *sync.Pool
+}
+
+func (f *pooledFile) Read(p []byte) (int, error) {
+	if f.r == nil {
+		return 0, fmt.Errorf("cannot read closed file")
+	}
+	return f.r.Read(p)
+}
+
+func (f *pooledFile) Close() error {
+	if f.r != nil {
+		f.r.Reset(nil)
+		f.pool.Put(f.r)
+		f.r = nil
+	}
+	return f.File.Close()
+}
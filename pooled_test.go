@@ -0,0 +1,36 @@
+package simplefs
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestOpenPooled(t *testing.T) {
+	pool := &sync.Pool{New: func() interface{} {
+		return bufio.NewReaderSize(nil, 16)
+	}}
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		if err := WriteString(fs, "file", "hello pooled world"); err != nil {
+			t.Fatalf("%s: WriteString() error: %v", name, err)
+		}
+		for i := 0; i < 3; i++ {
+			f, err := OpenPooled(fs, "file", pool)
+			if err != nil {
+				t.Fatalf("%s: OpenPooled() error: %v", name, err)
+			}
+			b, err := io.ReadAll(f)
+			if err != nil || string(b) != "hello pooled world" {
+				t.Fatalf("%s: ReadAll() returned %q, %v", name, b, err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatalf("%s: Close() error: %v", name, err)
+			}
+		}
+		if _, err := OpenPooled(fs, "missing", pool); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: OpenPooled(missing) returned %v, want %v", name, err, ErrNotFound)
+		}
+	}
+}
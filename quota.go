@@ -0,0 +1,230 @@
+package simplefs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var ErrQuotaExceeded = fmt.Errorf("quota exceeded")
+
+// WithQuota returns an FS that limits the total size of all files in fs to
+// maxBytes. The current usage is computed by walking fs on the first write,
+// and fs must implement Stater so that the size of replaced files is known.
+//
+// Bytes are accounted for as they are written: a Write that would push the
+// total past maxBytes writes nothing and returns ErrQuotaExceeded, and so
+// does the subsequent Close. Since Close still commits the bytes that were
+// accepted, a Create that exceeds the quota leaves a truncated file behind.
+// Create immediately frees the size of the file it replaces, and Remove and
+// RemoveAll free the size of what they remove. The quota is not
+// enforced for writes made to fs directly, or concurrently to the same path.
+func WithQuota(fs FS, maxBytes int64) FS {
+	return &quotaFS{fs: fs, maxBytes: maxBytes}
+}
+
+type quotaFS struct {
+	fs       FS
+	maxBytes int64
+
+	l       sync.Mutex
+	used    int64
+	counted bool
+}
+
+// init computes the current usage of fs if it hasn't been computed yet.
+func (fs *quotaFS) init() error {
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	if fs.counted {
+		return nil
+	}
+	var used int64
+	err := WalkFiles(fs.fs, ".", func(name string) error {
+		info, err := Stat(fs.fs, name)
+		if err != nil {
+			return err
+		}
+		used += info.Size()
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	fs.used, fs.counted = used, true
+	return nil
+}
+
+// size returns the size of the named file, or 0 if it doesn't exist or is
+// not a regular file, such as a directory, whose size is not counted.
+func (fs *quotaFS) size(name string) (int64, error) {
+	info, err := Stat(fs.fs, name)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !info.Mode().IsRegular() {
+		return 0, nil
+	}
+	return info.Size(), nil
+}
+
+// reserve adds n bytes to the usage, unless that would exceed the quota.
+func (fs *quotaFS) reserve(n int64) error {
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	if fs.used+n > fs.maxBytes {
+		return ErrQuotaExceeded
+	}
+	fs.used += n
+	return nil
+}
+
+func (fs *quotaFS) release(n int64) {
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	fs.used -= n
+}
+
+func (fs *quotaFS) Unwrap() FS {
+	return fs.fs
+}
+
+func (fs *quotaFS) Open(name string) (File, error) {
+	return fs.fs.Open(name)
+}
+
+func (fs *quotaFS) ReadDir(name string) ([]DirEntry, error) {
+	return fs.fs.ReadDir(name)
+}
+
+func (fs *quotaFS) Stat(name string) (os.FileInfo, error) {
+	return Stat(fs.fs, name)
+}
+
+func (fs *quotaFS) Lstat(name string) (os.FileInfo, error) {
+	return Lstat(fs.fs, name)
+}
+
+func (fs *quotaFS) Create(name string) (io.WriteCloser, error) {
+	if err := fs.init(); err != nil {
+		return nil, err
+	}
+	size, err := fs.size(name)
+	if err != nil {
+		return nil, err
+	}
+	w, err := fs.fs.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	fs.release(size)
+	return &quotaWriter{fs: fs, w: w}, nil
+}
+
+func (fs *quotaFS) Append(name string) (io.WriteCloser, error) {
+	if err := fs.init(); err != nil {
+		return nil, err
+	}
+	w, err := fs.fs.Append(name)
+	if err != nil {
+		return nil, err
+	}
+	return &quotaWriter{fs: fs, w: w}, nil
+}
+
+func (fs *quotaFS) Remove(name string) error {
+	if err := fs.init(); err != nil {
+		return err
+	}
+	size, err := fs.size(name)
+	if err != nil {
+		return err
+	}
+	if err := Remove(fs.fs, name); err != nil {
+		return err
+	}
+	fs.release(size)
+	return nil
+}
+
+func (fs *quotaFS) RemoveAll(name string) error {
+	if err := fs.init(); err != nil {
+		return err
+	}
+	var size int64
+	info, err := Stat(fs.fs, name)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	case info.IsDir():
+		err = WalkFiles(fs.fs, name, func(name string) error {
+			n, err := fs.size(name)
+			size += n
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		size = info.Size()
+	}
+	if err := RemoveAll(fs.fs, name); err != nil {
+		return err
+	}
+	fs.release(size)
+	return nil
+}
+
+// Rename renames oldName to newName, releasing the size of a file that is
+// replaced by it.
+func (fs *quotaFS) Rename(oldName, newName string) error {
+	if err := fs.init(); err != nil {
+		return err
+	}
+	var size int64
+	info, err := Stat(fs.fs, newName)
+	switch {
+	case err == nil && !info.IsDir():
+		size = info.Size()
+	case err != nil && !errors.Is(err, ErrNotFound):
+		return err
+	}
+	if err := Rename(fs.fs, oldName, newName); err != nil {
+		return err
+	}
+	fs.release(size)
+	return nil
+}
+
+type quotaWriter struct {
+	fs       *quotaFS
+	w        io.WriteCloser
+	exceeded bool
+}
+
+func (w *quotaWriter) Write(p []byte) (int, error) {
+	if err := w.fs.reserve(int64(len(p))); err != nil {
+		w.exceeded = true
+		return 0, err
+	}
+	n, err := w.w.Write(p)
+	if n < len(p) {
+		w.fs.release(int64(len(p) - n))
+	}
+	return n, err
+}
+
+func (w *quotaWriter) Close() error {
+	err := w.w.Close()
+	if w.exceeded && err == nil {
+		err = ErrQuotaExceeded
+	}
+	return err
+}
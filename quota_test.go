@@ -0,0 +1,82 @@
+package simplefs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithQuota(t *testing.T) {
+	mem := &MemFS{}
+	mem.SetString("existing", "0123456789")
+	fs := WithQuota(mem, 20)
+
+	if err := WriteString(fs, "a", "0123456789"); err != nil {
+		t.Fatalf("WriteString() within quota error: %v", err)
+	}
+
+	t.Run("Over-limit create", func(t *testing.T) {
+		w, err := fs.Create("b")
+		if err != nil {
+			t.Fatalf("Create() error: %v", err)
+		}
+		if _, err := w.Write([]byte("x")); !errors.Is(err, ErrQuotaExceeded) {
+			t.Fatalf("Write() returned %v, want ErrQuotaExceeded", err)
+		}
+		if err := w.Close(); !errors.Is(err, ErrQuotaExceeded) {
+			t.Fatalf("Close() returned %v, want ErrQuotaExceeded", err)
+		}
+		if err := WriteString(fs, "c", "x"); !errors.Is(err, ErrQuotaExceeded) {
+			t.Fatalf("WriteString() returned %v, want ErrQuotaExceeded", err)
+		}
+	})
+
+	t.Run("Freeing overwrite", func(t *testing.T) {
+		if err := WriteString(fs, "existing", "01234"); err != nil {
+			t.Fatalf("Overwrite error: %v", err)
+		}
+		w, err := fs.Append("a")
+		if err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+		if _, err := w.Write([]byte("01234")); err != nil {
+			t.Fatalf("Write() of freed space error: %v", err)
+		}
+		if _, err := w.Write([]byte("x")); !errors.Is(err, ErrQuotaExceeded) {
+			t.Fatalf("Write() returned %v, want ErrQuotaExceeded", err)
+		}
+		_ = w.Close()
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		if err := Remove(fs, "a"); err != nil {
+			t.Fatalf("Remove() error: %v", err)
+		}
+		if err := WriteString(fs, "dir/b", "0123456789"); err != nil {
+			t.Fatalf("WriteString() after Remove error: %v", err)
+		}
+		if err := RemoveAll(fs, "dir"); err != nil {
+			t.Fatalf("RemoveAll() error: %v", err)
+		}
+		if err := WriteString(fs, "c", "0123456789"); err != nil {
+			t.Fatalf("WriteString() after RemoveAll error: %v", err)
+		}
+	})
+}
+
+func TestWithQuotaRemoveDir(t *testing.T) {
+	osFS := OsFS(t.TempDir())
+	fs := WithQuota(osFS, 10)
+	if err := WriteString(fs, "file", "01234"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if err := MkdirAll(osFS, "empty"); err != nil {
+		t.Fatalf("MkdirAll() error: %v", err)
+	}
+	// Removing a directory frees nothing, whatever size Stat reports for it.
+	if err := Remove(fs, "empty"); err != nil {
+		t.Fatalf("Remove() of a directory error: %v", err)
+	}
+	if err := WriteString(fs, "other", "012345"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("WriteString() past the quota after removing a directory returned %v, want ErrQuotaExceeded", err)
+	}
+}
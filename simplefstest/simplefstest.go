@@ -0,0 +1,50 @@
+// Package simplefstest provides helpers for testing code that uses simplefs.
+package simplefstest
+
+import (
+	"testing"
+
+	"github.com/snechholt/simplefs"
+)
+
+// NewTempOsFS returns an OS file system rooted at a new temporary directory,
+// which is removed when the test and all its subtests complete.
+func NewTempOsFS(t testing.TB) simplefs.FS {
+	t.Helper()
+	return simplefs.OsFS(t.TempDir())
+}
+
+// NewMemFS returns a new, empty in-memory file system.
+func NewMemFS() *simplefs.MemFS {
+	return &simplefs.MemFS{}
+}
+
+// RunFileSystemTestT runs the conformance suite of simplefs.RunFileSystemSuite,
+// reporting each check as a subtest of t. Each subtest runs against a new,
+// empty file system returned by newFS, so subtests can be selected with -run.
+func RunFileSystemTestT(t *testing.T, newFS func() simplefs.FS) {
+	simplefs.RunFileSystemSuite(testingT{t}, newFS)
+}
+
+// testingT adapts a *testing.T to simplefs.Tester.
+type testingT struct {
+	t *testing.T
+}
+
+func (t testingT) Run(name string, fn func(t simplefs.Tester)) {
+	t.t.Run(name, func(t *testing.T) { fn(testingT{t}) })
+}
+
+func (t testingT) Errorf(format string, args ...interface{}) {
+	t.t.Helper()
+	t.t.Errorf(format, args...)
+}
+
+func (t testingT) Fatalf(format string, args ...interface{}) {
+	t.t.Helper()
+	t.t.Fatalf(format, args...)
+}
+
+func (t testingT) Helper() {
+	t.t.Helper()
+}
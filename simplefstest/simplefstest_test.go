@@ -0,0 +1,16 @@
+package simplefstest
+
+import (
+	"testing"
+
+	"github.com/snechholt/simplefs"
+)
+
+func TestNewFS(t *testing.T) {
+	t.Run("MemFS", func(t *testing.T) {
+		RunFileSystemTestT(t, func() simplefs.FS { return NewMemFS() })
+	})
+	t.Run("OsFS", func(t *testing.T) {
+		RunFileSystemTestT(t, func() simplefs.FS { return NewTempOsFS(t) })
+	})
+}
@@ -0,0 +1,43 @@
+package simplefs
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCreateSparse(t *testing.T) {
+	fss := map[string]FS{
+		"MemFS":    &MemFS{},
+		"OsFS":     OsFS(t.TempDir()),
+		"Fallback": Compressed(&MemFS{}),
+	}
+	for name, fs := range fss {
+		t.Run(name, func(t *testing.T) {
+			if err := WriteString(fs, "dir/file", "existing"); err != nil {
+				t.Fatalf("WriteString() error: %v", err)
+			}
+			for _, name := range []string{"dir/file", "dir/new"} {
+				if err := CreateSparse(fs, name, 1<<16); err != nil {
+					t.Fatalf("CreateSparse(%s) error: %v", name, err)
+				}
+				b, err := ReadFile(fs, name)
+				if err != nil {
+					t.Fatalf("ReadFile(%s) error: %v", name, err)
+				}
+				if !bytes.Equal(b, make([]byte, 1<<16)) {
+					t.Fatalf("ReadFile(%s) returned %d bytes, not all zero", name, len(b))
+				}
+			}
+		})
+	}
+}
+
+func TestCreateSparseNegativeSize(t *testing.T) {
+	fs := NewMemFSFromStrings(map[string]string{"file": "existing"})
+	if err := CreateSparse(fs, "file", -1); err == nil {
+		t.Fatalf("CreateSparse(-1) returned nil error")
+	}
+	if b, err := fs.Bytes("file"); err != nil || string(b) != "existing" {
+		t.Fatalf("Bytes(file) returned %q, %v, want the contents unchanged", b, err)
+	}
+}
@@ -0,0 +1,107 @@
+package simplefs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Tee returns an FS that writes to both primary and secondary, for example to
+// migrate from one FS to another while checking that they agree. Writers
+// returned by Create and Append write every chunk to primary and then to
+// secondary, and Close closes both, returning the errors of either. Errors
+// from secondary are wrapped with a message naming it. Open, ReadDir and
+// Stat use primary only.
+//
+// If secondary fails to create a file that primary has created, the writer
+// of primary is closed, so the file is left empty in primary.
+func Tee(primary, secondary FS) FS {
+	return &teeFS{fs: primary, secondary: secondary}
+}
+
+type teeFS struct {
+	fs        FS
+	secondary FS
+}
+
+func (fs *teeFS) Unwrap() FS {
+	return fs.fs
+}
+
+func (fs *teeFS) Open(name string) (File, error) {
+	return fs.fs.Open(name)
+}
+
+func (fs *teeFS) ReadDir(name string) ([]DirEntry, error) {
+	return fs.fs.ReadDir(name)
+}
+
+func (fs *teeFS) Stat(name string) (os.FileInfo, error) {
+	return Stat(fs.fs, name)
+}
+
+func (fs *teeFS) Lstat(name string) (os.FileInfo, error) {
+	return Lstat(fs.fs, name)
+}
+
+func (fs *teeFS) Create(name string) (io.WriteCloser, error) {
+	return fs.open("create", name, fs.fs.Create, fs.secondary.Create)
+}
+
+func (fs *teeFS) Append(name string) (io.WriteCloser, error) {
+	return fs.open("append", name, fs.fs.Append, fs.secondary.Append)
+}
+
+func (fs *teeFS) open(op, name string, primary, secondary func(string) (io.WriteCloser, error)) (io.WriteCloser, error) {
+	w, err := primary(name)
+	if err != nil {
+		return nil, err
+	}
+	w2, err := secondary(name)
+	if err != nil {
+		_ = w.Close()
+		return nil, fmt.Errorf("cannot %s '%s' in secondary FS: %w", op, name, err)
+	}
+	return &teeWriter{name: name, w: w, secondary: w2}, nil
+}
+
+// teeWriter writes to the writers of both file systems of a Tee.
+type teeWriter struct {
+	name      string
+	w         io.WriteCloser
+	secondary io.WriteCloser
+}
+
+// Write writes p to the primary writer, and then to the secondary one. The
+// count returned is that of the primary writer. If the secondary write fails,
+// its error is returned with the count of the primary, which has then written
+// bytes that the secondary has not, so the two files may have diverged.
+func (w *teeWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if _, err := w.secondary.Write(p); err != nil {
+		return n, fmt.Errorf("cannot write '%s' in secondary FS: %w", w.name, err)
+	}
+	return n, nil
+}
+
+// Sync implements Syncer by syncing both writers.
+func (w *teeWriter) Sync() error {
+	err := Sync(w.w)
+	if err2 := Sync(w.secondary); err2 != nil {
+		err = errors.Join(err, fmt.Errorf("cannot sync '%s' in secondary FS: %w", w.name, err2))
+	}
+	return err
+}
+
+// Close closes both writers, even if closing the first fails.
+func (w *teeWriter) Close() error {
+	err := w.w.Close()
+	if err2 := w.secondary.Close(); err2 != nil {
+		err = errors.Join(err, fmt.Errorf("cannot close '%s' in secondary FS: %w", w.name, err2))
+	}
+	return err
+}
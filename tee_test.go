@@ -0,0 +1,45 @@
+package simplefs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTee(t *testing.T) {
+	primary, secondary := OsFS(t.TempDir()), &MemFS{}
+	fs := Tee(primary, secondary)
+	if err := WriteString(fs, "dir/file", "hello"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	w, _ := fs.Append("dir/file")
+	_, _ = w.Write([]byte(" world"))
+	if err := Sync(w); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	for name, target := range map[string]FS{"Primary": primary, "Secondary": secondary, "Tee": fs} {
+		if b, err := ReadFile(target, "dir/file"); err != nil || string(b) != "hello world" {
+			t.Fatalf("%s contains %q, %v, want %q", name, b, err, "hello world")
+		}
+	}
+	if Unwrap(fs) != primary {
+		t.Fatalf("Unwrap() did not return the primary FS")
+	}
+
+	// Failures of the secondary FS are returned.
+	fs = Tee(&MemFS{}, WithQuota(&MemFS{}, 4))
+	w, _ = fs.Create("file")
+	if n, err := w.Write([]byte("too long")); n != 8 || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Write() past the quota of the secondary returned %d, %v, want 8 written by the primary and ErrQuotaExceeded", n, err)
+	}
+	_ = w.Close()
+
+	secondary = &MemFS{}
+	secondary.SetString("dir/file", "")
+	fs = Tee(&MemFS{}, secondary)
+	if _, err := fs.Create("dir"); !errors.Is(err, ErrIsDir) {
+		t.Fatalf("Create() of a directory in the secondary returned %v, want ErrIsDir", err)
+	}
+}
@@ -2,21 +2,37 @@ package simplefs
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"strings"
 )
 
+// RunFileSystemTest runs the conformance suite of RunFileSystemSuite outside
+// of a test. It stops at the first failure and returns its message, prefixed
+// by the name of the failing subtest, or "" if fs passes. All subtests share
+// fs, so fs should be empty.
 func RunFileSystemTest(fs FS) string {
+	var r runner
+	RunFileSystemSuite(&r, func() FS { return fs })
+	return r.msg
+}
+
+// RunFileSystemSuite runs a conformance test suite, reporting each check as a
+// subtest of t. Each subtest calls newFS for an empty file system and creates
+// the files it needs, so subtests can run on their own. If newFS returns the
+// same file system every time, the subtests run in order and share it. Tests
+// can use RunFileSystemTestT in package simplefstest, which runs the suite
+// with a *testing.T.
+func RunFileSystemSuite(t Tester, newFS func() FS) {
 	type File struct {
 		Name     string
 		Contents []byte
 	}
 
-	var t runner
-
-	assertFileContents := func(files ...File) {
+	assertFileContents := func(t Tester, fs FS, files ...File) {
+		t.Helper()
 		for _, f := range files {
 			r, err := fs.Open(f.Name)
 			if err != nil {
@@ -27,110 +43,100 @@ func RunFileSystemTest(fs FS) string {
 				t.Fatalf("%s: Read() error: %v", f.Name, err)
 			}
 			if bytes.Compare(b, f.Contents) != 0 {
-				t.Fatalf("%s: Wrong file contents: %v", f.Name, b)
+				t.Errorf("%s: Wrong file contents: %v", f.Name, b)
 			}
 		}
 	}
 
-	// Opening non-existing file returns ErrNotFound
-	t.Run("Opening non-existent file", func() {
-		r, err := fs.Open("file.txt")
-		if err != ErrNotFound {
-			t.Fatalf("Wrong error returned: %v", err)
-		}
-		if r != nil {
-			t.Fatalf("Non-nil reader returned")
-		}
-	})
-
-	// Creating a file and reading it back
-	file1 := File{Name: "file1", Contents: []byte{11, 12, 13}}
-	t.Run("Create file", func() {
-		w, err := fs.Create(file1.Name)
+	// write creates f with Create, Write and Close, failing the test on error.
+	write := func(t Tester, fs FS, f File) {
+		t.Helper()
+		w, err := fs.Create(f.Name)
 		if err != nil {
 			t.Fatalf("Create() error: %v", err)
 		}
-		if _, err := w.Write(file1.Contents); err != nil {
+		if _, err := w.Write(f.Contents); err != nil {
 			t.Fatalf("Write() error: %v", err)
 		}
 		if err := w.Close(); err != nil {
 			t.Fatalf("Close() error: %v", err)
 		}
+	}
 
-		assertFileContents(file1)
-	})
-
-	// Overwrite with new content
-	file1.Contents = []byte{12, 13, 14}
-	t.Run("Overwrite file", func() {
-		w, err := fs.Create(file1.Name)
+	// appendTo appends b to the named file, failing the test on error.
+	appendTo := func(t Tester, fs FS, name string, b []byte) {
+		t.Helper()
+		w, err := fs.Append(name)
 		if err != nil {
-			t.Fatalf("Create() error: %v", err)
+			t.Fatalf("Append() error: %v", err)
 		}
-		if _, err := w.Write(file1.Contents); err != nil {
+		if _, err := w.Write(b); err != nil {
 			t.Fatalf("Write() error: %v", err)
 		}
 		if err := w.Close(); err != nil {
 			t.Fatalf("Close() error: %v", err)
 		}
+	}
 
-		assertFileContents(file1)
+	// Opening non-existing file returns ErrNotFound
+	t.Run("Opening non-existent file", func(t Tester) {
+		fs := newFS()
+		r, err := fs.Open("file.txt")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Wrong error returned: %v", err)
+		}
+		if r != nil {
+			t.Errorf("Non-nil reader returned")
+		}
+	})
+
+	// Creating a file and reading it back
+	file1 := File{Name: "file1", Contents: []byte{11, 12, 13}}
+	t.Run("Create file", func(t Tester) {
+		fs := newFS()
+		write(t, fs, file1)
+		assertFileContents(t, fs, file1)
+	})
+
+	// Overwrite with new content
+	t.Run("Overwrite file", func(t Tester) {
+		fs := newFS()
+		write(t, fs, file1)
+		overwritten := File{Name: file1.Name, Contents: []byte{12, 13, 14}}
+		write(t, fs, overwritten)
+		assertFileContents(t, fs, overwritten)
 	})
 
 	// Assert that we read the correct file by name
 	file2 := File{Name: "file2", Contents: []byte{21, 22, 23}}
-	t.Run("Create another file", func() {
-		w, err := fs.Create(file2.Name)
-		if err != nil {
-			t.Fatalf("Create() error: %v", err)
-		}
-		if _, err := w.Write(file2.Contents); err != nil {
-			t.Fatalf("Write() error: %v", err)
-		}
-		if err := w.Close(); err != nil {
-			t.Fatalf("Close() error: %v", err)
-		}
-
-		assertFileContents(file1, file2)
+	t.Run("Create another file", func(t Tester) {
+		fs := newFS()
+		write(t, fs, file1)
+		write(t, fs, file2)
+		assertFileContents(t, fs, file1, file2)
 	})
 
 	// Append with additional bytes
-	append1 := []byte{15, 16}
-	file1.Contents = append(file1.Contents, append1...)
-	t.Run("Append to existing file", func() {
-		w, err := fs.Append(file1.Name)
-		if err != nil {
-			t.Fatalf("Append() error: %v", err)
-		}
-		if _, err := w.Write(append1); err != nil {
-			t.Fatalf("Write() error: %v", err)
-		}
-		if err := w.Close(); err != nil {
-			t.Fatalf("Close() error: %v", err)
-		}
-
-		assertFileContents(file1, file2)
+	t.Run("Append to existing file", func(t Tester) {
+		fs := newFS()
+		write(t, fs, file1)
+		write(t, fs, file2)
+		append1 := []byte{15, 16}
+		appendTo(t, fs, file1.Name, append1)
+		appended := File{Name: file1.Name, Contents: append(append([]byte{}, file1.Contents...), append1...)}
+		assertFileContents(t, fs, appended, file2)
 	})
 
 	file3 := File{Name: "file3", Contents: []byte{31, 32, 33}}
-	t.Run("Append to non-existing file", func() {
-		w, err := fs.Append(file3.Name)
-		if err != nil {
-			t.Fatalf("Append() error: %v", err)
-		}
-		if _, err := w.Write(file3.Contents); err != nil {
-			t.Fatalf("Write() error: %v", err)
-		}
-		if err := w.Close(); err != nil {
-			t.Fatalf("Close() error: %v", err)
-		}
-
-		assertFileContents(file1, file2, file3)
+	t.Run("Append to non-existing file", func(t Tester) {
+		fs := newFS()
+		appendTo(t, fs, file3.Name, file3.Contents)
+		assertFileContents(t, fs, file3)
 	})
 
 	// Now that we've covered the primitive Create, Append and Open we can use some utility
 	// functions for efficiency.
-	create := func(f File) error {
+	create := func(fs FS, f File) error {
 		w, err := fs.Create(f.Name)
 		if err != nil {
 			return err
@@ -144,15 +150,21 @@ func RunFileSystemTest(fs FS) string {
 	}
 
 	emptyFile := File{Name: "empty"}
-	t.Run("Create empty file", func() {
-		if err := create(emptyFile); err != nil {
+	t.Run("Create empty file", func(t Tester) {
+		fs := newFS()
+		if err := create(fs, emptyFile); err != nil {
 			t.Fatalf("Error creating file: %v", err)
 		}
-		assertFileContents(emptyFile)
+		assertFileContents(t, fs, emptyFile)
 	})
 
-	t.Run("ReadDir", func() {
+	t.Run("ReadDir", func(t Tester) {
+		fs := newFS()
 		files := []string{
+			file1.Name,
+			file2.Name,
+			file3.Name,
+			emptyFile.Name,
 			"dir1/file1A",
 			"dir1/file1B",
 			"dir2/file2A",
@@ -169,7 +181,7 @@ func RunFileSystemTest(fs FS) string {
 		for _, filename := range files {
 			split := strings.Split(filename, "/")
 			f := File{Name: filename, Contents: []byte(split[len(split)-1])}
-			if err := create(f); err != nil {
+			if err := create(fs, f); err != nil {
 				t.Fatalf("Error creating file: %v", err)
 			}
 		}
@@ -182,8 +194,10 @@ func RunFileSystemTest(fs FS) string {
 			"dir2/dir3": {file("file3A"), file("file3B")},
 			"dir4":      {dir("dir5")},
 		}
+		// The root can be listed and opened both as "." and as "".
+		tests[""] = tests["."]
 
-		t.Run("File.ReadDir", func() {
+		t.Run("File.ReadDir", func(t Tester) {
 			for _, n := range []int{-1, 1, 2, 3, 4, 5} {
 				for name, want := range tests {
 					dir, err := fs.Open(name)
@@ -209,46 +223,63 @@ func RunFileSystemTest(fs FS) string {
 						}
 					}
 					if !compareDirEntries(got, want) {
-						t.Fatalf("Open(%s).ReadDir(%d) returned %v, want %v", name, n, got, want)
+						t.Errorf("Open(%s).ReadDir(%d) returned %v, want %v", name, n, got, want)
+					}
+					if !dirEntriesSorted(got) {
+						t.Errorf("Open(%s).ReadDir(%d) returned unsorted entries %v", name, n, got)
 					}
 				}
 			}
 
-			t.Run("On file", func() {
+			t.Run("On file", func(t Tester) {
 				dir, err := fs.Open(file1.Name)
 				if err != nil {
 					t.Fatalf("Open(%s) returned error: %v", file1.Name, err)
 				}
-				if _, err = dir.ReadDir(-1); err == nil {
-					t.Fatalf("ReadDir() returned nil error")
+				if _, err = dir.ReadDir(-1); !errors.Is(err, ErrNotDir) {
+					t.Fatalf("ReadDir() on file returned %v, want ErrNotDir", err)
 				}
 			})
 		})
 
-		t.Run("fs.ReadDir", func() {
+		t.Run("fs.ReadDir", func(t Tester) {
 			for name, want := range tests {
 				got, err := fs.ReadDir(name)
 				if err != nil {
-					t.Fatalf("fs.ReadDir(%v) returned error: %v", name, err)
+					t.Errorf("fs.ReadDir(%v) returned error: %v", name, err)
+					continue
 				}
 				if !compareDirEntries(got, want) {
-					t.Fatalf("fs.ReadDir(%v) returned %v, want %v", name, got, want)
+					t.Errorf("fs.ReadDir(%v) returned %v, want %v", name, got, want)
+				}
+				if !dirEntriesSorted(got) {
+					t.Errorf("fs.ReadDir(%v) returned unsorted entries %v", name, got)
 				}
 			}
 
-			t.Run("On file", func() {
+			t.Run("On file", func(t Tester) {
 				dir, err := fs.Open(file1.Name)
 				if err != nil {
 					t.Fatalf("Open(%s) returned error: %v", file1.Name, err)
 				}
-				if _, err = dir.ReadDir(-1); err == nil {
-					t.Fatalf("ReadDir() returned nil error")
+				if _, err = dir.ReadDir(-1); !errors.Is(err, ErrNotDir) {
+					t.Fatalf("ReadDir() on file returned %v, want ErrNotDir", err)
+				}
+			})
+
+			t.Run("Read on directory", func(t Tester) {
+				dir, err := fs.Open("dir1")
+				if err != nil {
+					t.Fatalf("Open(dir1) returned error: %v", err)
+				}
+				if _, err := dir.Read(make([]byte, 1)); !errors.Is(err, ErrIsDir) {
+					t.Fatalf("Read() on directory returned %v, want ErrIsDir", err)
 				}
 			})
 
-			t.Run("On non-existent directory", func() {
+			t.Run("On non-existent directory", func(t Tester) {
 				_, err := fs.ReadDir("non-existent-dir")
-				if err != ErrNotFound {
+				if !errors.Is(err, ErrNotFound) {
 					t.Fatalf("Wrong error returned: %v", err)
 				}
 			})
@@ -256,15 +287,133 @@ func RunFileSystemTest(fs FS) string {
 
 	})
 
-	return t.msg
+	// The mutating operations below are optional. Each is only tested if the
+	// file systems from newFS implement the corresponding interface.
+	probe := newFS()
+	if _, ok := probe.(DirMaker); ok {
+		t.Run("MkdirAll", func(t Tester) {
+			fs := newFS()
+			if err := create(fs, file1); err != nil {
+				t.Fatalf("Error creating file: %v", err)
+			}
+			if err := MkdirAll(fs, "made/empty"); err != nil {
+				t.Fatalf("MkdirAll() error: %v", err)
+			}
+			if err := MkdirAll(fs, "made/empty"); err != nil {
+				t.Fatalf("MkdirAll() on existing directory error: %v", err)
+			}
+			entries, err := fs.ReadDir("made/empty")
+			if err != nil || len(entries) != 0 {
+				t.Errorf("ReadDir() on empty directory returned %v, %v", entries, err)
+			}
+			entries, err = fs.ReadDir("made")
+			if err != nil || !compareDirEntries(entries, []DirEntry{&dirEntry{name: "empty", isDir: true}}) {
+				t.Errorf("ReadDir() returned %v, %v, want [dir(empty)]", entries, err)
+			}
+			if err := MkdirAll(fs, file1.Name+"/dir"); err == nil {
+				t.Errorf("MkdirAll() below a file returned nil error")
+			}
+		})
+	}
+
+	if _, ok := probe.(Remover); ok {
+		t.Run("Remove", func(t Tester) {
+			fs := newFS()
+			f := File{Name: "removed/file", Contents: []byte{1}}
+			kept := File{Name: "kept/file", Contents: []byte{2}}
+			for _, f := range []File{f, kept} {
+				if err := create(fs, f); err != nil {
+					t.Fatalf("Error creating file: %v", err)
+				}
+			}
+			if err := Remove(fs, f.Name); err != nil {
+				t.Fatalf("Remove() error: %v", err)
+			}
+			if _, err := fs.Open(f.Name); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Open() after Remove() returned %v, want ErrNotFound", err)
+			}
+			if err := Remove(fs, f.Name); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Remove() on removed file returned %v, want ErrNotFound", err)
+			}
+			if err := Remove(fs, "removed"); err != nil {
+				t.Errorf("Remove() on empty directory error: %v", err)
+			}
+			if err := Remove(fs, "kept"); err == nil {
+				t.Errorf("Remove() on non-empty directory returned nil error")
+			}
+		})
+
+		t.Run("RemoveAll", func(t Tester) {
+			fs := newFS()
+			for _, name := range []string{"tree/a", "tree/sub/b", "tree/sub/deeper/c"} {
+				if err := create(fs, File{Name: name, Contents: []byte(name)}); err != nil {
+					t.Fatalf("Error creating file: %v", err)
+				}
+			}
+			if err := RemoveAll(fs, "tree"); err != nil {
+				t.Fatalf("RemoveAll() error: %v", err)
+			}
+			if _, err := fs.ReadDir("tree"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("ReadDir() after RemoveAll() returned %v, want ErrNotFound", err)
+			}
+			if _, err := fs.Open("tree/sub/b"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Open() after RemoveAll() returned %v, want ErrNotFound", err)
+			}
+			if err := RemoveAll(fs, "tree"); err != nil {
+				t.Errorf("RemoveAll() on missing path returned %v, want nil", err)
+			}
+		})
+	}
+
+	if _, ok := probe.(Renamer); ok {
+		t.Run("Rename", func(t Tester) {
+			fs := newFS()
+			from := File{Name: "from/file", Contents: []byte{4, 5, 6}}
+			if err := create(fs, from); err != nil {
+				t.Fatalf("Error creating file: %v", err)
+			}
+			to := File{Name: "to/dir/file", Contents: from.Contents}
+			if err := Rename(fs, from.Name, to.Name); err != nil {
+				t.Fatalf("Rename() error: %v", err)
+			}
+			assertFileContents(t, fs, to)
+			if _, err := fs.Open(from.Name); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Open() after Rename() returned %v, want ErrNotFound", err)
+			}
+			if err := Rename(fs, from.Name, to.Name); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Rename() of missing file returned %v, want ErrNotFound", err)
+			}
+
+			// Renaming onto an existing file replaces it.
+			if err := create(fs, from); err != nil {
+				t.Fatalf("Error creating file: %v", err)
+			}
+			if err := Rename(fs, to.Name, from.Name); err != nil {
+				t.Fatalf("Rename() onto existing file error: %v", err)
+			}
+			assertFileContents(t, fs, from)
+		})
+	}
+}
+
+// Tester is the subset of *testing.T used by RunFileSystemSuite, so that the
+// suite can run without importing package testing.
+type Tester interface {
+	Run(name string, fn func(t Tester))
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Helper()
 }
 
+// runner runs the suite outside of a test. Fatalf panics to abort the
+// subtest, and the first failure, from Errorf or Fatalf, stops all
+// subsequent subtests.
 type runner struct {
 	path []string
 	msg  string
 }
 
-func (r *runner) Run(name string, fn func()) {
+func (r *runner) Run(name string, fn func(t Tester)) {
 	if r.msg != "" {
 		return
 	}
@@ -276,7 +425,17 @@ func (r *runner) Run(name string, fn func()) {
 
 	}()
 	r.path = append(r.path, name)
-	fn()
+	fn(r)
+}
+
+func (r *runner) Helper() {}
+
+// Errorf records the failure, unless an earlier one was recorded, and lets
+// the subtest continue.
+func (r *runner) Errorf(s string, args ...interface{}) {
+	if r.msg == "" {
+		r.msg = fmt.Sprintf("'%s': %s", strings.Join(r.path, "/"), fmt.Sprintf(s, args...))
+	}
 }
 
 func (r *runner) Fatalf(s string, args ...interface{}) {
@@ -297,3 +456,12 @@ func compareDirEntries(entries1, entries2 []DirEntry) bool {
 	}
 	return true
 }
+
+func dirEntriesSorted(entries []DirEntry) bool {
+	for i := 1; i < len(entries); i++ {
+		if entries[i-1].Name() > entries[i].Name() {
+			return false
+		}
+	}
+	return true
+}
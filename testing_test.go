@@ -0,0 +1,22 @@
+package simplefs
+
+import (
+	"strings"
+	"testing"
+)
+
+// createOnlyFS fails the suite by creating files that cannot be read back.
+type createOnlyFS struct {
+	MemFS
+}
+
+func (fs *createOnlyFS) Open(name string) (File, error) {
+	return nil, notFound("open", name)
+}
+
+func TestRunFileSystemTestReportsSubtest(t *testing.T) {
+	msg := RunFileSystemTest(&createOnlyFS{})
+	if want := "'Create file': Open(file1) error: "; !strings.HasPrefix(msg, want) {
+		t.Fatalf("RunFileSystemTest() returned %q, want prefix %q", msg, want)
+	}
+}
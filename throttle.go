@@ -0,0 +1,315 @@
+package simplefs
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ContextOpener is implemented by file systems whose files can be opened with
+// a context that governs the lifetime of the returned File.
+type ContextOpener interface {
+	OpenContext(ctx context.Context, name string) (File, error)
+}
+
+// OpenContext opens the named file using ctx if fs implements
+// ContextOpener, and falls back to Open otherwise.
+func OpenContext(ctx context.Context, fs FS, name string) (File, error) {
+	if o, ok := fs.(ContextOpener); ok {
+		return o.OpenContext(ctx, name)
+	}
+	return fs.Open(name)
+}
+
+// WithReadThrottle returns an FS that limits the aggregate read throughput of
+// all files opened from it to bytesPerSec, using a token bucket that allows
+// bursts of up to one second worth of bytes. Each Read waits until enough
+// tokens are available, and reads at most one burst at a time. Files opened
+// with OpenContext stop waiting and return the context's error once it is
+// done. Writes and directory listings are not throttled.
+func WithReadThrottle(fs FS, bytesPerSec int64) FS {
+	return &readThrottleFS{fs: fs, bucket: newTokenBucket(bytesPerSec, realClock{})}
+}
+
+type readThrottleFS struct {
+	fs     FS
+	bucket *tokenBucket
+}
+
+func (fs *readThrottleFS) Unwrap() FS {
+	return fs.fs
+}
+
+func (fs *readThrottleFS) Open(name string) (File, error) {
+	return fs.OpenContext(context.Background(), name)
+}
+
+func (fs *readThrottleFS) OpenContext(ctx context.Context, name string) (File, error) {
+	f, err := OpenContext(ctx, fs.fs, name)
+	if err != nil {
+		return nil, err
+	}
+	if isDirFile(f) {
+		return f, nil
+	}
+	return &throttledFile{File: f, ctx: ctx, bucket: fs.bucket}, nil
+}
+
+func (fs *readThrottleFS) ReadDir(name string) ([]DirEntry, error) {
+	return fs.fs.ReadDir(name)
+}
+
+func (fs *readThrottleFS) Stat(name string) (os.FileInfo, error) {
+	return Stat(fs.fs, name)
+}
+
+func (fs *readThrottleFS) Lstat(name string) (os.FileInfo, error) {
+	return Lstat(fs.fs, name)
+}
+
+func (fs *readThrottleFS) Create(name string) (io.WriteCloser, error) {
+	return fs.fs.Create(name)
+}
+
+func (fs *readThrottleFS) Append(name string) (io.WriteCloser, error) {
+	return fs.fs.Append(name)
+}
+
+type throttledFile struct {
+	File
+	ctx    context.Context
+	bucket *tokenBucket
+}
+
+func (f *throttledFile) Read(p []byte) (int, error) {
+	if int64(len(p)) > f.bucket.burst {
+		p = p[:f.bucket.burst]
+	}
+	if err := f.bucket.wait(f.ctx, int64(len(p))); err != nil {
+		return 0, err
+	}
+	n, err := f.File.Read(p)
+	f.bucket.refund(int64(len(p) - n))
+	return n, err
+}
+
+// tokenBucket is a token bucket rate limiter refilling at rate tokens per
+// second up to burst tokens. Waiters reserve tokens up front, which may drive
+// the balance negative, and then sleep until the balance would have been
+// refilled.
+type tokenBucket struct {
+	rate  float64
+	burst int64
+	clock Clock
+
+	l      sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(bytesPerSec int64, clock Clock) *tokenBucket {
+	if bytesPerSec < 1 {
+		bytesPerSec = 1
+	}
+	return &tokenBucket{rate: float64(bytesPerSec), burst: bytesPerSec, clock: clock, tokens: float64(bytesPerSec), last: clock.Now()}
+}
+
+// wait takes n tokens from the bucket, waiting until they are available or
+// ctx is done. Tokens are returned to the bucket if ctx is done first.
+func (b *tokenBucket) wait(ctx context.Context, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.l.Lock()
+	now := b.clock.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.l.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	if err := b.clock.Sleep(ctx, delay); err != nil {
+		b.refund(n)
+		return err
+	}
+	return nil
+}
+
+// refund returns n unused tokens to the bucket.
+func (b *tokenBucket) refund(n int64) {
+	if n <= 0 {
+		return
+	}
+	b.l.Lock()
+	defer b.l.Unlock()
+	b.tokens += float64(n)
+}
+
+// Clock is the source of time for Throttled. Tests can provide their own to
+// make throttling deterministic.
+type Clock interface {
+	Now() time.Time
+
+	// Sleep waits for d to pass, or until ctx is done, in which case it
+	// returns the context's error.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// realClock is the Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ThrottleOption configures the FS returned by Throttled.
+type ThrottleOption func(fs *throttledFS)
+
+// WithClock makes Throttled measure and wait for time using clock instead of
+// the time package.
+func WithClock(clock Clock) ThrottleOption {
+	return func(fs *throttledFS) {
+		fs.clock = clock
+	}
+}
+
+// Throttled returns an FS that simulates slow storage. Every operation waits
+// for opLatency before it is passed on to fs, and reads from and writes to
+// files share a throughput limit of bytesPerSec, enforced like
+// WithReadThrottle. Files opened with OpenContext, and their reads, stop
+// waiting once the context is done.
+func Throttled(fs FS, bytesPerSec int64, opLatency time.Duration, opts ...ThrottleOption) FS {
+	t := &throttledFS{fs: fs, latency: opLatency, clock: realClock{}}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.bucket = newTokenBucket(bytesPerSec, t.clock)
+	return t
+}
+
+type throttledFS struct {
+	fs      FS
+	latency time.Duration
+	clock   Clock
+	bucket  *tokenBucket
+}
+
+func (fs *throttledFS) Unwrap() FS {
+	return fs.fs
+}
+
+// delay waits for the operation latency.
+func (fs *throttledFS) delay(ctx context.Context) error {
+	if fs.latency <= 0 {
+		return ctx.Err()
+	}
+	return fs.clock.Sleep(ctx, fs.latency)
+}
+
+func (fs *throttledFS) Open(name string) (File, error) {
+	return fs.OpenContext(context.Background(), name)
+}
+
+func (fs *throttledFS) OpenContext(ctx context.Context, name string) (File, error) {
+	if err := fs.delay(ctx); err != nil {
+		return nil, err
+	}
+	f, err := OpenContext(ctx, fs.fs, name)
+	if err != nil {
+		return nil, err
+	}
+	if isDirFile(f) {
+		return f, nil
+	}
+	return &throttledFile{File: f, ctx: ctx, bucket: fs.bucket}, nil
+}
+
+func (fs *throttledFS) ReadDir(name string) ([]DirEntry, error) {
+	if err := fs.delay(context.Background()); err != nil {
+		return nil, err
+	}
+	return fs.fs.ReadDir(name)
+}
+
+func (fs *throttledFS) Stat(name string) (os.FileInfo, error) {
+	if err := fs.delay(context.Background()); err != nil {
+		return nil, err
+	}
+	return Stat(fs.fs, name)
+}
+
+func (fs *throttledFS) Lstat(name string) (os.FileInfo, error) {
+	if err := fs.delay(context.Background()); err != nil {
+		return nil, err
+	}
+	return Lstat(fs.fs, name)
+}
+
+func (fs *throttledFS) Create(name string) (io.WriteCloser, error) {
+	if err := fs.delay(context.Background()); err != nil {
+		return nil, err
+	}
+	w, err := fs.fs.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &throttledWriter{w: w, bucket: fs.bucket}, nil
+}
+
+func (fs *throttledFS) Append(name string) (io.WriteCloser, error) {
+	if err := fs.delay(context.Background()); err != nil {
+		return nil, err
+	}
+	w, err := fs.fs.Append(name)
+	if err != nil {
+		return nil, err
+	}
+	return &throttledWriter{w: w, bucket: fs.bucket}, nil
+}
+
+type throttledWriter struct {
+	w      io.WriteCloser
+	bucket *tokenBucket
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if int64(len(chunk)) > w.bucket.burst {
+			chunk = chunk[:w.bucket.burst]
+		}
+		if err := w.bucket.wait(context.Background(), int64(len(chunk))); err != nil {
+			return written, err
+		}
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+func (w *throttledWriter) Close() error {
+	return w.w.Close()
+}
@@ -0,0 +1,115 @@
+package simplefs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWithReadThrottle(t *testing.T) {
+	mem := &MemFS{}
+	mem.SetBytes("file", bytes.Repeat([]byte{1}, 1500))
+	fs := WithReadThrottle(mem, 1000)
+
+	// The first second worth of bytes is available as a burst, the
+	// remaining 500 bytes take about half a second.
+	start := time.Now()
+	b, err := ReadFile(fs, "file")
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if len(b) != 1500 {
+		t.Fatalf("ReadFile() returned %d bytes", len(b))
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond || elapsed > 3*time.Second {
+		t.Fatalf("Reading 1500 bytes at 1000 bytes/s took %v", elapsed)
+	}
+}
+
+func TestWithReadThrottleContext(t *testing.T) {
+	mem := &MemFS{}
+	mem.SetBytes("file", bytes.Repeat([]byte{1}, 100))
+	fs := WithReadThrottle(mem, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	f, err := OpenContext(ctx, fs, "file")
+	if err != nil {
+		t.Fatalf("OpenContext() error: %v", err)
+	}
+	start := time.Now()
+	_, err = io.ReadAll(f)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ReadAll() returned %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Cancelled read took %v", elapsed)
+	}
+}
+
+// fakeClock is a Clock whose Sleep advances the time instantly.
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.now = c.now.Add(d)
+	c.slept += d
+	return nil
+}
+
+func TestThrottled(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	fs := Throttled(&MemFS{}, 100, 10*time.Millisecond, WithClock(clock))
+
+	// The first 100 bytes are available as a burst, the remaining 150 bytes
+	// take 1.5 seconds, on top of the latency of Create.
+	if err := WriteFile(fs, "file", bytes.Repeat([]byte{1}, 250)); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if want := 1510 * time.Millisecond; clock.slept != want {
+		t.Fatalf("Writing 250 bytes slept %v, want %v", clock.slept, want)
+	}
+
+	// The bucket is empty, so reading the 250 bytes takes 2.5 seconds. The
+	// bucket refills during the latency of Open, so that is included. Each
+	// Read waits for its tokens before reading, so the final Read, which
+	// finds the end of the file, waits for a full burst of one more second
+	// before its unused tokens are refunded.
+	clock.slept = 0
+	b, err := ReadFile(fs, "file")
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if len(b) != 250 {
+		t.Fatalf("ReadFile() returned %d bytes", len(b))
+	}
+	if want := 3500 * time.Millisecond; clock.slept != want {
+		t.Fatalf("Reading 250 bytes slept %v, want %v", clock.slept, want)
+	}
+
+	clock.slept = 0
+	if _, err := fs.ReadDir("."); err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if want := 10 * time.Millisecond; clock.slept != want {
+		t.Fatalf("ReadDir() slept %v, want %v", clock.slept, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := OpenContext(ctx, fs, "file"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("OpenContext() with cancelled context returned %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,45 @@
+package simplefs
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestTouch(t *testing.T) {
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	mem := &MemFS{}
+	mem.SetString("existing", "data")
+	mem.root.Get("existing").ModTime = old
+	dir := t.TempDir()
+	if err := WriteString(OsFS(dir), "existing", "data"); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if err := os.Chtimes(path.Join(dir, "existing"), old, old); err != nil {
+		t.Fatalf("Chtimes() error: %v", err)
+	}
+
+	for name, fs := range map[string]FS{"MemFS": mem, "OsFS": OsFS(dir)} {
+		if err := Touch(fs, "dir/marker"); err != nil {
+			t.Fatalf("%s: Touch() error: %v", name, err)
+		}
+		if b, err := ReadFile(fs, "dir/marker"); err != nil || len(b) != 0 {
+			t.Fatalf("%s: ReadFile() returned %q, %v, want empty file", name, b, err)
+		}
+
+		if err := Touch(fs, "existing"); err != nil {
+			t.Fatalf("%s: Touch() error: %v", name, err)
+		}
+		if b, err := ReadFile(fs, "existing"); err != nil || string(b) != "data" {
+			t.Fatalf("%s: ReadFile() returned %q, %v, want %q", name, b, err, "data")
+		}
+		info, err := Stat(fs, "existing")
+		if err != nil {
+			t.Fatalf("%s: Stat() error: %v", name, err)
+		}
+		if !info.ModTime().After(old) {
+			t.Fatalf("%s: ModTime() returned %v, want after %v", name, info.ModTime(), old)
+		}
+	}
+}
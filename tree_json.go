@@ -0,0 +1,76 @@
+package simplefs
+
+import (
+	"encoding/json"
+	"path"
+)
+
+// treeNode is the JSON representation of a file or directory produced by
+// MemFS.MarshalJSON and TreeJSON. Files have a size but no children, and
+// directories have a (possibly empty) array of children sorted by name.
+type treeNode struct {
+	Name     string       `json:"name"`
+	IsDir    bool         `json:"isDir"`
+	Size     *int64       `json:"size,omitempty"`
+	Children *[]*treeNode `json:"children,omitempty"`
+}
+
+// MarshalJSON encodes the whole tree as nested objects of the form
+// {"name":..,"isDir":..,"size":..,"children":[..]}. The root is named ".".
+// Symbolic links are encoded as files and not followed.
+func (fs *MemFS) MarshalJSON() ([]byte, error) {
+	fs.init()
+	fs.l.RLock()
+	defer fs.l.RUnlock()
+	return json.Marshal(memTreeNode(fs.root, "."))
+}
+
+func memTreeNode(node *dirNode, name string) *treeNode {
+	t := &treeNode{Name: name, IsDir: node.IsDirectory()}
+	if !t.IsDir {
+		size := int64(len(node.B))
+		t.Size = &size
+		return t
+	}
+	children := make([]*treeNode, len(node.Children))
+	for i, child := range node.Children {
+		children[i] = memTreeNode(child, child.Name)
+	}
+	t.Children = &children
+	return t
+}
+
+// TreeJSON encodes the tree rooted at root like MemFS.MarshalJSON, using only
+// ReadDir so that it works for any FS. Sizes are included when the entries'
+// Info is available. The top-level object is named after the last element
+// of root.
+func TreeJSON(fs FS, root string) ([]byte, error) {
+	t, err := readTreeNode(fs, root, path.Base(path.Clean(root)))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(t)
+}
+
+func readTreeNode(fs FS, dir, name string) (*treeNode, error) {
+	entries, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sortDirEntries(entries)
+	children := make([]*treeNode, len(entries))
+	for i, entry := range entries {
+		if entry.IsDir() {
+			if children[i], err = readTreeNode(fs, path.Join(dir, entry.Name()), entry.Name()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		children[i] = &treeNode{Name: entry.Name()}
+		if info, err := entry.Info(); err == nil {
+			size := info.Size()
+			children[i].Size = &size
+		}
+	}
+	return &treeNode{Name: name, IsDir: true, Children: &children}, nil
+}
@@ -0,0 +1,49 @@
+package simplefs
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTreeJSON(t *testing.T) {
+	files := map[string]string{"b": "bb", "a/z": "z", "a/sub/y": "yyy", "a/c": ""}
+	want := `{"name":".","isDir":true,"children":[` +
+		`{"name":"a","isDir":true,"children":[` +
+		`{"name":"c","isDir":false,"size":0},` +
+		`{"name":"sub","isDir":true,"children":[{"name":"y","isDir":false,"size":3}]},` +
+		`{"name":"z","isDir":false,"size":1}]},` +
+		`{"name":"b","isDir":false,"size":2}]}`
+
+	mem := NewMemFSFromStrings(files)
+	b, err := json.Marshal(mem)
+	if err != nil {
+		t.Fatalf("json.Marshal() error: %v", err)
+	}
+	if string(b) != want {
+		t.Fatalf("json.Marshal() returned\n%s\nwant\n%s", b, want)
+	}
+
+	osFS := OsFS(t.TempDir())
+	for name, s := range files {
+		if err := WriteString(osFS, name, s); err != nil {
+			t.Fatalf("WriteString() error: %v", err)
+		}
+	}
+	for name, fs := range map[string]FS{"MemFS": mem, "OsFS": osFS} {
+		b, err := TreeJSON(fs, ".")
+		if err != nil {
+			t.Fatalf("%s: TreeJSON() error: %v", name, err)
+		}
+		if string(b) != want {
+			t.Fatalf("%s: TreeJSON() returned\n%s\nwant\n%s", name, b, want)
+		}
+	}
+
+	b, err = TreeJSON(mem, "a/sub")
+	if err != nil {
+		t.Fatalf("TreeJSON(a/sub) error: %v", err)
+	}
+	if want := `{"name":"sub","isDir":true,"children":[{"name":"y","isDir":false,"size":3}]}`; string(b) != want {
+		t.Fatalf("TreeJSON(a/sub) returned %s, want %s", b, want)
+	}
+}
@@ -0,0 +1,30 @@
+package simplefs
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUnwrap(t *testing.T) {
+	mem := &MemFS{}
+	encrypted, err := Encrypted(mem, bytes.Repeat([]byte{1}, 16))
+	if err != nil {
+		t.Fatalf("Encrypted() error: %v", err)
+	}
+	fs := WithNameLimits(Compressed(WithQuota(Cached(WithReadThrottle(WithDerived(encrypted, nil), 1), 1), 1)), 1, 1)
+
+	var depth int
+	for next := Unwrap(fs); next != nil; next = Unwrap(next) {
+		fs = next
+		depth++
+	}
+	if fs != FS(mem) {
+		t.Fatalf("Unwrapping ended at %T, want *MemFS", fs)
+	}
+	if depth != 7 {
+		t.Fatalf("Unwrapped %d layers, want 7", depth)
+	}
+	if Unwrap(mem) != nil {
+		t.Fatalf("Unwrap(*MemFS) returned non-nil")
+	}
+}
@@ -0,0 +1,25 @@
+package simplefs
+
+// StatFS is implemented by file systems that can report how full their
+// backing store is. Used and capacity are in bytes, and a capacity of -1
+// means the store is unbounded.
+type StatFS interface {
+	Usage() (used, capacity int64, err error)
+}
+
+// Usage implements StatFS. Used is the total size of all files, and the
+// capacity is reported as -1 since a MemFS has no fixed limit.
+func (fs *MemFS) Usage() (used, capacity int64, err error) {
+	return fs.Size(), -1, nil
+}
+
+// Usage implements StatFS. Used is the usage accounted by the quota, and the
+// capacity is the quota itself, regardless of the capacity of the wrapped FS.
+func (fs *quotaFS) Usage() (used, capacity int64, err error) {
+	if err := fs.init(); err != nil {
+		return 0, 0, err
+	}
+	fs.l.Lock()
+	defer fs.l.Unlock()
+	return fs.used, fs.maxBytes, nil
+}
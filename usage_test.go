@@ -0,0 +1,48 @@
+package simplefs
+
+import "testing"
+
+func TestUsage(t *testing.T) {
+	t.Run("Quota", func(t *testing.T) {
+		mem := NewMemFSFromStrings(map[string]string{"a": "12345"})
+		fs := WithQuota(mem, 100)
+		assertUsage := func(wantUsed int64) {
+			used, capacity, err := fs.(StatFS).Usage()
+			if err != nil {
+				t.Fatalf("Usage() error: %v", err)
+			}
+			if used != wantUsed || capacity != 100 {
+				t.Fatalf("Usage() returned %d, %d, want %d, 100", used, capacity, wantUsed)
+			}
+		}
+		assertUsage(5)
+
+		w, _ := fs.Create("dir/b")
+		_, _ = w.Write([]byte("0123456789"))
+		_ = w.Close()
+		assertUsage(15)
+
+		if err := Remove(fs, "a"); err != nil {
+			t.Fatalf("Remove() error: %v", err)
+		}
+		assertUsage(10)
+	})
+
+	t.Run("MemFS", func(t *testing.T) {
+		fs := NewMemFSFromStrings(map[string]string{"a": "123", "dir/b": "45"})
+		used, capacity, err := fs.Usage()
+		if err != nil || used != 5 || capacity != -1 {
+			t.Fatalf("Usage() returned %d, %d, %v, want 5, -1, nil", used, capacity, err)
+		}
+	})
+
+	t.Run("OsFS", func(t *testing.T) {
+		used, capacity, err := OsFS(t.TempDir()).(StatFS).Usage()
+		if err != nil {
+			t.Skipf("Usage() not supported: %v", err)
+		}
+		if capacity <= 0 || used < 0 || used > capacity {
+			t.Fatalf("Usage() returned %d, %d", used, capacity)
+		}
+	})
+}
@@ -1,19 +1,111 @@
 package simplefs
 
-import "io"
+import (
+	"bytes"
+	"io"
+	"os"
+	"sort"
+)
 
+// writeCloser writes to w and calls closeFn on the first Close. Later calls
+// to Write and Close return os.ErrClosed, so that closeFn never runs twice.
 type writeCloser struct {
 	w       io.Writer
 	closeFn func() error
+	closed  bool
 }
 
 func (w *writeCloser) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
 	return w.w.Write(p)
 }
 
 func (w *writeCloser) Close() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
 	if w.closeFn != nil {
 		return w.closeFn()
 	}
 	return nil
 }
+
+// readFileNames returns the names of the entries of dir that are not
+// directories, in the order returned by ReadDir.
+func readFileNames(fs FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// isDirFile reports whether f is known to be a directory.
+func isDirFile(f File) bool {
+	if s, ok := f.(statFile); ok {
+		info, err := s.Stat()
+		return err == nil && info.IsDir()
+	}
+	return false
+}
+
+type bytesFile struct {
+	*bytes.Reader
+	name string
+}
+
+func newBytesFile(name string, b []byte) *bytesFile {
+	return &bytesFile{Reader: bytes.NewReader(b), name: name}
+}
+
+func (f *bytesFile) Close() error {
+	return nil
+}
+
+func (f *bytesFile) ReadDir(n int) ([]DirEntry, error) {
+	return nil, &FSError{Op: "readdir", Path: f.name, Err: ErrNotDir}
+}
+
+// zeroReader is an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// nextDirEntries pops the next n entries off the remaining entries, following
+// the paging semantics of os.File.ReadDir: if n > 0 at most n entries are
+// returned and io.EOF is returned once no entries remain; if n <= 0 all
+// remaining entries are returned with a nil error.
+func nextDirEntries(remaining *[]DirEntry, n int) ([]DirEntry, error) {
+	entries := *remaining
+	if n <= 0 {
+		*remaining = entries[len(entries):]
+		return entries, nil
+	}
+	if len(entries) == 0 {
+		return entries, io.EOF
+	}
+	size := n
+	if size > len(entries) {
+		size = len(entries)
+	}
+	*remaining = entries[size:]
+	return entries[:size], nil
+}
+
+func sortDirEntries(entries []DirEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+}
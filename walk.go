@@ -0,0 +1,124 @@
+package simplefs
+
+import (
+	"errors"
+	iofs "io/fs"
+	"path"
+)
+
+// WalkFiles calls fn for the path of every regular file in the tree rooted at
+// root, depth-first and in the order returned by ReadDir. Directories are
+// descended into but not passed to fn. If fn returns an error the walk stops
+// and that error is returned.
+func WalkFiles(fs FS, root string, fn func(path string) error) error {
+	entries, err := fs.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := path.Join(root, entry.Name())
+		if entry.IsDir() {
+			err = WalkFiles(fs, name, fn)
+		} else {
+			err = fn(name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SkipDir and SkipAll are the values of io/fs of the same name. Returned from
+// a WalkDirFunc, SkipDir skips the current directory, or the rest of the
+// directory containing the current file, and SkipAll skips everything
+// remaining.
+var (
+	SkipDir = iofs.SkipDir
+	SkipAll = iofs.SkipAll
+)
+
+// WalkDirFunc is the type of the function called by WalkDir for each file
+// and directory. It follows the contract of io/fs.WalkDirFunc.
+type WalkDirFunc func(path string, d DirEntry, err error) error
+
+// WalkDir walks the tree rooted at root like io/fs.WalkDir, calling fn for
+// every file and directory, including root, in lexical order. The DirEntry
+// passed to fn is the one returned by ReadDir, so its Type and Info are
+// available without a Stat per entry. Only root is described with Stat, and
+// it is assumed to be a directory if fs does not implement Stater. If
+// reading a directory fails, fn is called a second time for it with the
+// error.
+func WalkDir(fs FS, root string, fn WalkDirFunc) error {
+	var d DirEntry
+	info, err := Stat(fs, root)
+	switch {
+	case errors.Is(err, ErrNotImplemented):
+		d = &dirEntry{name: path.Base(root), isDir: true}
+		err = nil
+	case err == nil:
+		d = newDirEntry(info)
+	}
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fs, root, d, fn)
+	}
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDir(fs FS, name string, d DirEntry, fn WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	entries, err := fs.ReadDir(name)
+	if err != nil {
+		if err = fn(name, d, err); err != nil {
+			if err == SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if err := walkDir(fs, path.Join(name, entry.Name()), entry, fn); err != nil {
+			if err == SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// FindFirst walks the tree rooted at root like WalkDir and returns the path
+// of the first file or directory for which match returns true. The walk stops
+// at the first match. If nothing matches it returns an error wrapping
+// ErrNotFound, and if the walk fails it returns that error.
+func FindFirst(fs FS, root string, match func(path string, d DirEntry) bool) (string, error) {
+	var found string
+	var ok bool
+	err := WalkDir(fs, root, func(path string, d DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if match(path, d) {
+			found, ok = path, true
+			return SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", notFound("find", root)
+	}
+	return found, nil
+}
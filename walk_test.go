@@ -0,0 +1,149 @@
+package simplefs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWalkFiles(t *testing.T) {
+	fs := &MemFS{}
+	for _, name := range []string{"a", "dir/b", "dir/sub/c", "dir/sub/d", "e", "empty/sub/f"} {
+		fs.SetString(name, name)
+	}
+
+	var got []string
+	err := WalkFiles(fs, ".", func(path string) error {
+		got = append(got, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkFiles() error: %v", err)
+	}
+	want := "a,dir/b,dir/sub/c,dir/sub/d,e,empty/sub/f"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("WalkFiles() visited %v, want %v", got, want)
+	}
+
+	got = nil
+	_ = WalkFiles(fs, "dir", func(path string) error {
+		got = append(got, path)
+		return nil
+	})
+	if strings.Join(got, ",") != "dir/b,dir/sub/c,dir/sub/d" {
+		t.Fatalf("WalkFiles(dir) visited %v", got)
+	}
+
+	stop := errors.New("stop")
+	got = nil
+	err = WalkFiles(fs, ".", func(path string) error {
+		got = append(got, path)
+		if path == "dir/sub/c" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("WalkFiles() returned %v, want %v", err, stop)
+	}
+	if strings.Join(got, ",") != "a,dir/b,dir/sub/c" {
+		t.Fatalf("WalkFiles() visited %v after stopping", got)
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	for name, fs := range map[string]FS{"MemFS": &MemFS{}, "OsFS": OsFS(t.TempDir())} {
+		for _, file := range []string{"a", "dir/b", "dir/sub/c", "dir/z", "e"} {
+			w, _ := fs.Create(file)
+			_, _ = w.Write([]byte(file))
+			_ = w.Close()
+		}
+
+		walk := func(root string, skip map[string]error) (string, error) {
+			var visited []string
+			err := WalkDir(fs, root, func(path string, d DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				entry := path
+				if d.IsDir() {
+					entry += "/"
+				} else {
+					info, err := d.Info()
+					if err != nil {
+						return err
+					}
+					entry += ":" + string(rune('0'+info.Size()))
+				}
+				visited = append(visited, entry)
+				return skip[path]
+			})
+			return strings.Join(visited, ","), err
+		}
+
+		tests := []struct {
+			root string
+			skip map[string]error
+			want string
+		}{
+			{".", nil, "./,a:1,dir/,dir/b:5,dir/sub/,dir/sub/c:9,dir/z:5,e:1"},
+			{"dir", nil, "dir/,dir/b:5,dir/sub/,dir/sub/c:9,dir/z:5"},
+			{"dir/b", nil, "dir/b:5"},
+			{".", map[string]error{"dir/sub": SkipDir}, "./,a:1,dir/,dir/b:5,dir/sub/,dir/z:5,e:1"},
+			{".", map[string]error{"dir/b": SkipDir}, "./,a:1,dir/,dir/b:5,e:1"},
+			{".", map[string]error{"dir/b": SkipAll}, "./,a:1,dir/,dir/b:5"},
+			{".", map[string]error{".": SkipDir}, "./"},
+		}
+		for _, test := range tests {
+			got, err := walk(test.root, test.skip)
+			if err != nil {
+				t.Fatalf("%s: WalkDir(%s) error: %v", name, test.root, err)
+			}
+			if got != test.want {
+				t.Fatalf("%s: WalkDir(%s) with %v visited %s, want %s", name, test.root, test.skip, got, test.want)
+			}
+		}
+
+		stop := errors.New("stop")
+		if _, err := walk(".", map[string]error{"dir/b": stop}); err != stop {
+			t.Fatalf("%s: WalkDir() returned %v, want the error returned by fn", name, err)
+		}
+		if _, err := walk("missing", nil); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: WalkDir() of missing root returned %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestFindFirst(t *testing.T) {
+	fs := &MemFS{}
+	for _, name := range []string{"a", "dir/config.json", "dir/sub/config.json", "e/config.json"} {
+		fs.SetString(name, name)
+	}
+
+	var visited []string
+	got, err := FindFirst(fs, ".", func(path string, d DirEntry) bool {
+		visited = append(visited, path)
+		return d.Name() == "config.json"
+	})
+	if err != nil || got != "dir/config.json" {
+		t.Fatalf("FindFirst() returned %q, %v, want %q, nil", got, err, "dir/config.json")
+	}
+	if strings.Join(visited, ",") != ".,a,dir,dir/config.json" {
+		t.Fatalf("FindFirst() visited %v after the match", visited)
+	}
+
+	got, err = FindFirst(fs, "dir/sub", func(path string, d DirEntry) bool { return d.IsDir() })
+	if err != nil || got != "dir/sub" {
+		t.Fatalf("FindFirst(dir/sub) returned %q, %v, want the root", got, err)
+	}
+
+	_, err = FindFirst(fs, ".", func(path string, d DirEntry) bool { return false })
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FindFirst() returned %v when nothing matches, want ErrNotFound", err)
+	}
+
+	_, err = FindFirst(fs, "missing", func(path string, d DirEntry) bool { return true })
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FindFirst(missing) returned %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,118 @@
+package simplefs
+
+import (
+	"strings"
+	"sync"
+)
+
+// Op describes the kind of change reported by an Event.
+type Op int
+
+const (
+	OpCreate Op = iota + 1
+	OpAppend
+	OpRemove
+	OpRename
+	OpWrite
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpCreate:
+		return "create"
+	case OpAppend:
+		return "append"
+	case OpRemove:
+		return "remove"
+	case OpRename:
+		return "rename"
+	case OpWrite:
+		return "write"
+	}
+	return "unknown"
+}
+
+// Event describes a change to the file or directory at Path.
+type Event struct {
+	Path string
+	Op   Op
+}
+
+// Watcher is implemented by file systems that can report changes.
+//
+// Watch returns a channel receiving an Event for every change to name, or to
+// anything beneath it if name is a directory, and a function that stops the
+// watch and closes the channel. The channel is buffered, and events that do
+// not fit in the buffer because the consumer is too slow are dropped rather
+// than blocking writers.
+type Watcher interface {
+	Watch(name string) (<-chan Event, func(), error)
+}
+
+// Watch watches the named path for changes. It returns ErrNotImplemented if fs
+// does not implement Watcher.
+func Watch(fs FS, name string) (<-chan Event, func(), error) {
+	if w, ok := fs.(Watcher); ok {
+		return w.Watch(name)
+	}
+	return nil, nil, ErrNotImplemented
+}
+
+// watchBufferSize is the buffer size of channels returned from Watch.
+const watchBufferSize = 64
+
+type watch struct {
+	path string
+	ch   chan Event
+}
+
+// matches reports whether an event for path concerns the watched path, i.e.
+// whether path is the watched path or beneath it. Removing a parent of the
+// watched path also matches.
+func (w *watch) matches(path string, op Op) bool {
+	if w.path == "" || path == w.path || strings.HasPrefix(path, w.path+"/") {
+		return true
+	}
+	return op == OpRemove && strings.HasPrefix(w.path, path+"/")
+}
+
+// watchers is a set of watches which MemFS emits events to.
+type watchers struct {
+	l       sync.Mutex
+	watches []*watch
+}
+
+func (ws *watchers) add(path string) (<-chan Event, func()) {
+	ws.l.Lock()
+	defer ws.l.Unlock()
+	w := &watch{path: path, ch: make(chan Event, watchBufferSize)}
+	ws.watches = append(ws.watches, w)
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			ws.l.Lock()
+			defer ws.l.Unlock()
+			for i, other := range ws.watches {
+				if other == w {
+					ws.watches = append(ws.watches[:i:i], ws.watches[i+1:]...)
+					break
+				}
+			}
+			close(w.ch)
+		})
+	}
+	return w.ch, stop
+}
+
+func (ws *watchers) emit(path string, op Op) {
+	ws.l.Lock()
+	defer ws.l.Unlock()
+	for _, w := range ws.watches {
+		if w.matches(path, op) {
+			select {
+			case w.ch <- Event{Path: path, Op: op}:
+			default:
+			}
+		}
+	}
+}
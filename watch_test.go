@@ -0,0 +1,83 @@
+package simplefs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemFSWatch(t *testing.T) {
+	fs := &MemFS{}
+	events, stop, err := fs.Watch("dir")
+	if err != nil {
+		t.Fatalf("Watch() error: %v", err)
+	}
+
+	fs.SetString("other", "x")
+	fs.SetString("dir/a", "a")
+	w, _ := fs.Append("dir/a")
+	_ = w.Close()
+	fs.SetString("dir/sub/b", "b")
+	_ = fs.Remove("dir/a")
+	_ = fs.RemoveAll("dir")
+
+	want := []Event{
+		{Path: "dir/a", Op: OpCreate},
+		{Path: "dir/a", Op: OpAppend},
+		{Path: "dir/sub/b", Op: OpCreate},
+		{Path: "dir/a", Op: OpRemove},
+		{Path: "dir", Op: OpRemove},
+	}
+	for _, want := range want {
+		if got := <-events; got != want {
+			t.Fatalf("Got event %v, want %v", got, want)
+		}
+	}
+
+	stop()
+	stop()
+	fs.SetString("dir/c", "c")
+	if _, ok := <-events; ok {
+		t.Fatalf("Received event after stop")
+	}
+}
+
+func TestMemFSWatchUncleanPath(t *testing.T) {
+	for _, name := range []string{"dir/", "./dir", "dir/sub/..", "/dir"} {
+		fs := &MemFS{}
+		events, stop, err := fs.Watch(name)
+		if err != nil {
+			t.Fatalf("Watch(%s) error: %v", name, err)
+		}
+		fs.SetString("other", "x")
+		fs.SetString("dir/a", "a")
+		// Events are buffered before SetString returns.
+		select {
+		case got := <-events:
+			if want := (Event{Path: "dir/a", Op: OpCreate}); got != want {
+				t.Fatalf("Watch(%s): got event %v, want %v", name, got, want)
+			}
+		default:
+			t.Fatalf("Watch(%s): no event for dir/a", name)
+		}
+		stop()
+	}
+}
+
+func TestMemFSWatchSlowConsumer(t *testing.T) {
+	fs := &MemFS{}
+	events, stop, _ := fs.Watch(".")
+	defer stop()
+	// Writers must not block even though nobody is receiving
+	for i := 0; i < 2*watchBufferSize; i++ {
+		fs.SetString("file", "x")
+	}
+	if len(events) != watchBufferSize {
+		t.Fatalf("Got %d buffered events, want %d", len(events), watchBufferSize)
+	}
+}
+
+func TestWatchNotImplemented(t *testing.T) {
+	if _, _, err := Watch(OsFS(t.TempDir()), "."); !errors.Is(err, ErrNotImplemented) {
+		t.Fatalf("Watch() returned %v, want ErrNotImplemented", err)
+	}
+}